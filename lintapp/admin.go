@@ -0,0 +1,54 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package lintapp
+
+import (
+	"net/http"
+	"path"
+	"strconv"
+
+	"google.golang.org/appengine"
+	"google.golang.org/appengine/datastore"
+	"google.golang.org/appengine/user"
+)
+
+// adminHandlerFunc is a handlerFunc restricted to application
+// administrators. The admin URLs are also protected in app.yaml.
+type adminHandlerFunc func(http.ResponseWriter, *http.Request) error
+
+func (f adminHandlerFunc) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	handlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+		if !user.IsAdmin(appengine.NewContext(r)) {
+			return &appError{Status: 403}
+		}
+		return f(w, r)
+	}).ServeHTTP(w, r)
+}
+
+// serveAdminRaw returns the stored entity for a package without decoding it.
+// The entity is returned as JSON with base64 encoded data, or as the raw gob
+// data when the download parameter is set.
+func serveAdminRaw(w http.ResponseWriter, r *http.Request) error {
+	c := appengine.NewContext(r)
+	key := r.FormValue("importPath")
+	var spkg storePackage
+	if err := datastore.Get(c, datastore.NewKey(c, "Package", key, 0, nil), &spkg); err != nil {
+		if err == datastore.ErrNoSuchEntity {
+			err = &appError{Status: 404, Message: "Package not stored."}
+		}
+		return err
+	}
+	if r.FormValue("download") == "" {
+		return writeJSONResponse(w, 200, &spkg)
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+path.Base(key)+`.gob"`)
+	w.Header().Set("Content-Length", strconv.Itoa(len(spkg.Data)))
+	w.Header().Set("X-Lint-Version", strconv.Itoa(spkg.Version))
+	_, err := w.Write(spkg.Data)
+	return err
+}
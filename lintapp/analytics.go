@@ -0,0 +1,229 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package lintapp
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+	"google.golang.org/appengine"
+	"google.golang.org/appengine/log"
+	"google.golang.org/appengine/taskqueue"
+	"google.golang.org/appengine/urlfetch"
+)
+
+// Event is a usage event recorded by Analytics.
+type Event struct {
+	Time time.Time `json:"time"`
+
+	// Name is one of "view", "refresh", "error" or "version_mismatch".
+	Name string `json:"name"`
+
+	// Path is the import path for view and refresh events and the request
+	// path for error events.
+	Path string `json:"path"`
+
+	// Outcome is "ok", "queued" for a queued refresh, the HTTP status of an
+	// error or the stored version of a version mismatch.
+	Outcome string `json:"outcome"`
+
+	// DurationMs is the time spent handling the request in milliseconds.
+	DurationMs float64 `json:"duration_ms"`
+
+	// FromCache is true if a viewed package was not linted by the request.
+	FromCache bool `json:"from_cache"`
+
+	// RequestID is the App Engine ID of the request, for finding the
+	// request in the logs.
+	RequestID string `json:"request_id"`
+}
+
+// id returns an ID for e that is the same each time e is recorded, so that a
+// batch retried after a partial failure does not store e twice.
+func (e *Event) id() string {
+	h := sha1.Sum([]byte(fmt.Sprintf("%s\x00%d\x00%s\x00%s", e.RequestID, e.Time.UnixNano(), e.Name, e.Path)))
+	return fmt.Sprintf("%x", h)
+}
+
+// Analytics receives usage events. Events are recorded in batches by the
+// events task, so that requests do not wait for them to be stored.
+type Analytics interface {
+	Record(c context.Context, events []*Event) error
+}
+
+// analytics is the configured Analytics. Events are discarded by default.
+var analytics Analytics = noopAnalytics{}
+
+type noopAnalytics struct{}
+
+func (noopAnalytics) Record(c context.Context, events []*Event) error { return nil }
+
+// multiAnalytics records events with each of its Analytics.
+type multiAnalytics []Analytics
+
+func (m multiAnalytics) Record(c context.Context, events []*Event) error {
+	var first error
+	for _, a := range m {
+		if err := a.Record(c, events); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// bigQueryAnalytics streams events into a BigQuery table in the
+// application's project. The table must have columns matching the JSON
+// encoding of Event.
+type bigQueryAnalytics struct {
+	Dataset string
+	Table   string
+}
+
+func (a *bigQueryAnalytics) Record(c context.Context, events []*Event) error {
+	token, _, err := appengine.AccessToken(c, "https://www.googleapis.com/auth/bigquery.insertdata")
+	if err != nil {
+		return err
+	}
+	rows := make([]interface{}, len(events))
+	for i, e := range events {
+		rows[i] = map[string]interface{}{"insertId": e.id(), "json": e}
+	}
+	body, err := json.Marshal(map[string]interface{}{"rows": rows})
+	if err != nil {
+		return err
+	}
+	u := fmt.Sprintf("https://www.googleapis.com/bigquery/v2/projects/%s/datasets/%s/tables/%s/insertAll",
+		appengine.AppID(c), a.Dataset, a.Table)
+	req, err := http.NewRequest("POST", u, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := urlfetch.Client(c).Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		p, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("bigquery insert: %d %s", resp.StatusCode, p)
+	}
+	return nil
+}
+
+const (
+	// eventsTaskPath is the path of the handler that records batches of
+	// events.
+	eventsTaskPath = "/-/task/events"
+
+	// eventsQueue is the task queue for events. The empty string selects
+	// the default queue.
+	eventsQueue = ""
+
+	// eventBatchSize is the number of events buffered by an instance
+	// before they are queued for recording.
+	eventBatchSize = 50
+)
+
+// eventFlushInterval is the longest an event is buffered before it is
+// queued for recording, if the instance gets another event.
+var eventFlushInterval = time.Minute
+
+// pendingEvents holds the events of the instance not yet queued.
+var pendingEvents eventBuffer
+
+// eventBuffer collects events into batches.
+type eventBuffer struct {
+	mu     sync.Mutex
+	events []*Event
+	first  time.Time // when the first buffered event was added
+}
+
+// add adds e to the buffer. It returns the buffered events, emptying the
+// buffer, when there are eventBatchSize of them or the first was added more
+// than eventFlushInterval before t, and nil otherwise.
+func (b *eventBuffer) add(e *Event, t time.Time) []*Event {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if len(b.events) == 0 {
+		b.first = t
+	}
+	b.events = append(b.events, e)
+	if len(b.events) < eventBatchSize && t.Sub(b.first) < eventFlushInterval {
+		return nil
+	}
+	events := b.events
+	b.events = nil
+	return events
+}
+
+// take returns the buffered events, emptying the buffer.
+func (b *eventBuffer) take() []*Event {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	events := b.events
+	b.events = nil
+	return events
+}
+
+// recordEvent records an event that started at the given time. The event is
+// buffered and queued with others for the events task. Errors are logged
+// and otherwise ignored.
+func recordEvent(c context.Context, start time.Time, e *Event) {
+	if _, ok := analytics.(noopAnalytics); ok {
+		return
+	}
+	e.Time = start
+	e.DurationMs = float64(now().Sub(start)) / float64(time.Millisecond)
+	e.RequestID = appengine.RequestID(c)
+	if events := pendingEvents.add(e, now()); events != nil {
+		queueEvents(c, events)
+	}
+}
+
+// queueEvents adds a task to record events.
+func queueEvents(c context.Context, events []*Event) {
+	if len(events) == 0 {
+		return
+	}
+	body, err := json.Marshal(events)
+	if err == nil {
+		t := &taskqueue.Task{
+			Path:    eventsTaskPath,
+			Payload: body,
+			Header:  http.Header{"Content-Type": {"application/json"}},
+			Method:  "POST",
+		}
+		_, err = taskqueue.Add(c, t, eventsQueue)
+	}
+	if err != nil {
+		log.Warningf(c, "Queueing %d events: %v", len(events), err)
+	}
+}
+
+// serveEventsTask records a batch of events queued by recordEvent. Errors
+// are returned so that the task is retried.
+func serveEventsTask(w http.ResponseWriter, r *http.Request) error {
+	if r.Header.Get("X-AppEngine-QueueName") == "" {
+		return &appError{Status: 403}
+	}
+	c := appengine.NewContext(r)
+	var events []*Event
+	if err := json.NewDecoder(r.Body).Decode(&events); err != nil {
+		log.Errorf(c, "Dropping events: %v", err)
+		return nil
+	}
+	return analytics.Record(c, events)
+}
@@ -0,0 +1,58 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package lintapp
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEventBuffer(t *testing.T) {
+	t0 := time.Date(2016, 5, 1, 12, 0, 0, 0, time.UTC)
+	var b eventBuffer
+	for i := 0; i < eventBatchSize-1; i++ {
+		if events := b.add(&Event{Name: "view"}, t0); events != nil {
+			t.Fatalf("add %d returned %d events, want nil", i, len(events))
+		}
+	}
+	if events := b.add(&Event{Name: "view"}, t0); len(events) != eventBatchSize {
+		t.Fatalf("add of event %d returned %d events, want %d", eventBatchSize, len(events), eventBatchSize)
+	}
+
+	if events := b.add(&Event{Name: "view"}, t0); events != nil {
+		t.Fatalf("add after flush returned %d events, want nil", len(events))
+	}
+	if events := b.add(&Event{Name: "error"}, t0.Add(eventFlushInterval)); len(events) != 2 {
+		t.Fatalf("add after eventFlushInterval returned %d events, want 2", len(events))
+	}
+
+	b.add(&Event{Name: "refresh"}, t0)
+	if events := b.take(); len(events) != 1 || events[0].Name != "refresh" {
+		t.Errorf("take returned %v, want the refresh event", events)
+	}
+	if events := b.take(); events != nil {
+		t.Errorf("take of empty buffer returned %v, want nil", events)
+	}
+}
+
+func TestEventID(t *testing.T) {
+	t0 := time.Date(2016, 5, 1, 12, 0, 0, 0, time.UTC)
+	e := &Event{RequestID: "r1", Time: t0, Name: "view", Path: "github.com/user/a"}
+	if id := e.id(); id != (&Event{RequestID: "r1", Time: t0, Name: "view", Path: "github.com/user/a", DurationMs: 5}).id() {
+		t.Errorf("id changed with the duration")
+	}
+	for _, other := range []*Event{
+		{RequestID: "r2", Time: t0, Name: "view", Path: "github.com/user/a"},
+		{RequestID: "r1", Time: t0.Add(time.Nanosecond), Name: "view", Path: "github.com/user/a"},
+		{RequestID: "r1", Time: t0, Name: "error", Path: "github.com/user/a"},
+		{RequestID: "r1", Time: t0, Name: "view", Path: "github.com/user/b"},
+	} {
+		if other.id() == e.id() {
+			t.Errorf("%+v has the same id as %+v", other, e)
+		}
+	}
+}
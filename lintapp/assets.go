@@ -0,0 +1,47 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package lintapp
+
+import (
+	"io/ioutil"
+	"path/filepath"
+)
+
+// assetVersions maps the URL paths of the static files in the assets
+// directory to hashes of their contents. The static file handlers in app.yaml
+// must set application_readable for the files to be hashed.
+var assetVersions = hashAssets("assets")
+
+// hashAssets returns the hashes of the files in dir by URL path. Files that
+// cannot be read are left out, and their URLs are not versioned.
+func hashAssets(dir string) map[string]string {
+	versions := make(map[string]string)
+	fis, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return versions
+	}
+	for _, fi := range fis {
+		if fi.IsDir() {
+			continue
+		}
+		data, err := ioutil.ReadFile(filepath.Join(dir, fi.Name()))
+		if err != nil {
+			continue
+		}
+		versions["/"+fi.Name()] = fileHash(data)[:10]
+	}
+	return versions
+}
+
+// assetFn returns the URL of the static asset with the given path, with a
+// version parameter so that browsers fetch the asset again when it changes.
+func assetFn(p string) string {
+	if v := assetVersions[p]; v != "" {
+		return p + "?v=" + v
+	}
+	return p
+}
@@ -0,0 +1,25 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package lintapp
+
+import (
+	"io/ioutil"
+	"testing"
+)
+
+func TestAssetFn(t *testing.T) {
+	data, err := ioutil.ReadFile("assets/favicon.ico")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := assetFn("/favicon.ico"), "/favicon.ico?v="+fileHash(data)[:10]; got != want {
+		t.Errorf("assetFn(/favicon.ico) = %q, want %q", got, want)
+	}
+	if got := assetFn("/missing.css"); got != "/missing.css" {
+		t.Errorf("assetFn(/missing.css) = %q, want unversioned URL", got)
+	}
+}
@@ -0,0 +1,86 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package lintapp
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"golang.org/x/net/context"
+	"google.golang.org/appengine"
+	"google.golang.org/appengine/datastore"
+	"google.golang.org/appengine/log"
+)
+
+// auditAnalytics stores events as AuditEvent entities in the datastore for
+// export with /-/admin/audit. Each batch is stored with one PutMulti. Keys
+// are named by Event.id, so recording a batch again overwrites it.
+type auditAnalytics struct{}
+
+func (auditAnalytics) Record(c context.Context, events []*Event) error {
+	keys := make([]*datastore.Key, len(events))
+	for i := range keys {
+		keys[i] = datastore.NewKey(c, "AuditEvent", events[i].id(), 0, nil)
+	}
+	_, err := datastore.PutMulti(c, keys, events)
+	return err
+}
+
+const auditDateFormat = "2006-01-02"
+
+// serveAdminAudit writes the audit events from the start date up to and
+// including the end date as JSON Lines, oldest first. The dates are in UTC
+// and formatted as YYYY-MM-DD. The end date defaults to the start date. If
+// reading the events fails once the response has started, the last line is
+// an object with the error in its error field.
+func serveAdminAudit(w http.ResponseWriter, r *http.Request) error {
+	start, err := time.Parse(auditDateFormat, r.FormValue("start"))
+	if err != nil {
+		return &appError{Status: 400, Message: "Bad start parameter."}
+	}
+	end := start
+	if s := r.FormValue("end"); s != "" {
+		if end, err = time.Parse(auditDateFormat, s); err != nil {
+			return &appError{Status: 400, Message: "Bad end parameter."}
+		}
+	}
+	end = end.AddDate(0, 0, 1)
+
+	c := appengine.NewContext(r)
+	it := datastore.NewQuery("AuditEvent").
+		Filter("Time >=", start).
+		Filter("Time <", end).
+		Order("Time").
+		Run(c)
+	w.Header().Set("Content-Type", "application/x-ndjson; charset=utf-8")
+	w.Header().Set("Content-Disposition", "attachment; filename=audit-"+start.Format(auditDateFormat)+".jsonl")
+	w.WriteHeader(200)
+	flusher, _ := w.(http.Flusher)
+	enc := json.NewEncoder(w)
+	for n := 1; ; n++ {
+		var e Event
+		_, err := it.Next(&e)
+		if err == datastore.Done {
+			return nil
+		}
+		if err != nil {
+			// The response has started, so the error is reported in a
+			// last line instead of the status.
+			log.Errorf(c, "Reading audit events: %v", err)
+			enc.Encode(map[string]string{"error": err.Error()})
+			return nil
+		}
+		if err := enc.Encode(&e); err != nil {
+			log.Warningf(c, "Writing audit events: %v", err)
+			return nil
+		}
+		if flusher != nil && n%100 == 0 {
+			flusher.Flush()
+		}
+	}
+}
@@ -0,0 +1,55 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package lintapp
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"google.golang.org/appengine"
+	"google.golang.org/appengine/aetest"
+)
+
+func TestAuditAnalytics(t *testing.T) {
+	i, err := aetest.NewInstance(&aetest.Options{StronglyConsistentDatastore: true})
+	if err != nil {
+		t.Skipf("App Engine development server not available: %v", err)
+	}
+	defer i.Close()
+	r, err := i.NewRequest("GET", "/-/admin/audit?start=2016-05-01", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t0 := time.Date(2016, 5, 1, 12, 0, 0, 0, time.UTC)
+	events := []*Event{
+		{Time: t0.Add(time.Minute), Name: "view", Path: "github.com/user/b"},
+		{Time: t0, Name: "view", Path: "github.com/user/a"},
+		{Time: t0.AddDate(0, 0, 1), Name: "view", Path: "github.com/user/c"},
+	}
+	if err := (auditAnalytics{}).Record(appengine.NewContext(r), events); err != nil {
+		t.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	if err := serveAdminAudit(w, r); err != nil {
+		t.Fatal(err)
+	}
+	var paths []string
+	s := bufio.NewScanner(w.Body)
+	for s.Scan() {
+		var e Event
+		if err := json.Unmarshal(s.Bytes(), &e); err != nil {
+			t.Fatalf("line %q: %v", s.Text(), err)
+		}
+		paths = append(paths, e.Path)
+	}
+	if len(paths) != 2 || paths[0] != "github.com/user/a" || paths[1] != "github.com/user/b" {
+		t.Errorf("exported paths %v, want [github.com/user/a github.com/user/b]", paths)
+	}
+}
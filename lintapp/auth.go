@@ -0,0 +1,67 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package lintapp
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"golang.org/x/net/context"
+	"google.golang.org/appengine/user"
+)
+
+var (
+	// requireLogin restricts the site to signed in users.
+	requireLogin = false
+
+	// authSecret, if set, restricts the site to requests with the secret in
+	// the X-Auth-Secret header. When both requireLogin and authSecret are
+	// set, either is sufficient.
+	authSecret = ""
+)
+
+// authExempt lists the paths served without authentication: robots.txt for
+// crawlers, and the bot page linked from the User-Agent of fetches.
+var authExempt = map[string]bool{
+	"/robots.txt": true,
+	"/-/bot":      true,
+}
+
+// authEnabled reports whether the site is private.
+func authEnabled() bool {
+	return requireLogin || authSecret != ""
+}
+
+// checkAuth returns an error if the site is private and r is not
+// authenticated. Signed out users of a site requiring login are redirected to
+// the login page and nil is returned with handled set.
+func checkAuth(c context.Context, w http.ResponseWriter, r *http.Request) (handled bool, err error) {
+	if !authEnabled() {
+		return false, nil
+	}
+	// App Engine removes the header from external requests.
+	if authExempt[r.URL.Path] || r.Header.Get("X-AppEngine-QueueName") != "" {
+		return false, nil
+	}
+	if authSecret != "" && subtle.ConstantTimeCompare([]byte(r.Header.Get("X-Auth-Secret")), []byte(authSecret)) == 1 {
+		return false, nil
+	}
+	if requireLogin {
+		if user.Current(c) != nil {
+			return false, nil
+		}
+		if r.Method == "GET" || r.Method == "HEAD" {
+			loginURL, err := user.LoginURL(c, r.URL.String())
+			if err != nil {
+				return false, err
+			}
+			http.Redirect(w, r, loginURL, http.StatusFound)
+			return true, nil
+		}
+	}
+	return false, &appError{Status: 401}
+}
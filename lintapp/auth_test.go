@@ -0,0 +1,69 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package lintapp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+func TestCheckAuthSecret(t *testing.T) {
+	defer func(s string) { authSecret = s }(authSecret)
+
+	tests := []struct {
+		secret string
+		path   string
+		header string
+		ok     bool
+	}{
+		{"", "/github.com/a/b", "", true},
+		{"s3cret", "/github.com/a/b", "s3cret", true},
+		{"s3cret", "/github.com/a/b", "wrong", false},
+		{"s3cret", "/github.com/a/b", "", false},
+		{"s3cret", "/robots.txt", "", true},
+		{"s3cret", "/-/bot", "", true},
+		{"s3cret", "/-/health", "", false},
+	}
+	for _, tt := range tests {
+		authSecret = tt.secret
+		r, _ := http.NewRequest("GET", tt.path, nil)
+		if tt.header != "" {
+			r.Header.Set("X-Auth-Secret", tt.header)
+		}
+		handled, err := checkAuth(context.Background(), httptest.NewRecorder(), r)
+		if handled {
+			t.Errorf("checkAuth(%q, %q) handled the request", tt.path, tt.header)
+		}
+		if (err == nil) != tt.ok {
+			t.Errorf("checkAuth(%q, %q) with secret %q = %v, want ok %v", tt.path, tt.header, tt.secret, err, tt.ok)
+		}
+		if e, ok := err.(*appError); err != nil && (!ok || e.Status != 401) {
+			t.Errorf("checkAuth(%q, %q) = %#v, want 401 appError", tt.path, tt.header, err)
+		}
+	}
+}
+
+func TestSetCacheControlPrivate(t *testing.T) {
+	defer func(s string) { authSecret = s }(authSecret)
+	for _, tt := range []struct {
+		secret, want string
+	}{
+		{"", "public, max-age=60"},
+		{"s3cret", "private, no-store"},
+	} {
+		authSecret = tt.secret
+		w := httptest.NewRecorder()
+		setCacheControl(w, time.Minute)
+		if got := w.Header().Get("Cache-Control"); got != tt.want {
+			t.Errorf("Cache-Control with secret %q = %q, want %q", tt.secret, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package lintapp
+
+import (
+	"net/http"
+	"regexp"
+	"strconv"
+
+	"google.golang.org/appengine"
+
+	"github.com/ReturnPath/gddo/gosrc"
+)
+
+// A baseline is a lint result stored under a name, such as a release, for
+// comparison with later results. Baselines are not kept across changes of
+// the stored package version.
+
+var baselineNamePat = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// parseBaselineRequest returns the import path, options and baseline name
+// of a baseline request.
+func parseBaselineRequest(r *http.Request) (string, lintOptions, string, error) {
+	importPath := r.FormValue("importPath")
+	if err := checkPathLimits(importPath); err != nil {
+		return "", lintOptions{}, "", err
+	}
+	if !fetcher.ValidPath(importPath) {
+		return "", lintOptions{}, "", gosrc.NotFoundError{Message: "bad path"}
+	}
+	name := r.FormValue("baseline")
+	if !baselineNamePat.MatchString(name) {
+		return "", lintOptions{}, "", &appError{Status: 400, Message: "Bad baseline parameter."}
+	}
+	opts, err := parseLintOptions(r)
+	return importPath, opts, name, err
+}
+
+// serveAdminBaseline stores the current result for a package as a named
+// baseline.
+func serveAdminBaseline(w http.ResponseWriter, r *http.Request) error {
+	if r.Method != "POST" {
+		return &appError{Status: 405}
+	}
+	importPath, opts, name, err := parseBaselineRequest(r)
+	if err != nil {
+		return err
+	}
+	c := appengine.NewContext(r)
+	pkg, err := getPackage(c, opts.key(importPath))
+	if pkg == nil && err == nil {
+		pkg, err = runLint(r, importPath, opts)
+	}
+	if err != nil {
+		return err
+	}
+	if err := store.PutBaseline(c, opts.key(pkg.Path), name, storedPackage(pkg)); err != nil {
+		return err
+	}
+	return writeTextResponse(w, 200, "Stored baseline "+name+" for "+pkg.Path+".\n")
+}
+
+// serveAgainstBaseline lints the current version of a package and responds
+// with the problems that are not in the named baseline. The status is 422 if
+// there are new problems, for use as a gate in continuous integration. A HEAD
+// request gets the status and the X-Lint-Problems header only.
+func serveAgainstBaseline(w http.ResponseWriter, r *http.Request) error {
+	importPath, opts, name, err := parseBaselineRequest(r)
+	if err != nil {
+		return err
+	}
+	c := appengine.NewContext(r)
+	baseline, err := store.GetBaseline(c, opts.key(importPath), name)
+	if err != nil {
+		return err
+	}
+	if baseline == nil {
+		return &appError{Status: 404, Message: "Baseline not found."}
+	}
+	pkg, err := runLint(r, importPath, opts)
+	if err != nil {
+		return err
+	}
+	filterByConfidence(r, pkg)
+	removeBaselineProblems(pkg, baseline)
+	return writeGateResponse(w, r, pkg)
+}
+
+// writeGateResponse writes the remaining problems in pkg with status 422 if
+// there are any and 200 otherwise. The number of problems is in the
+// X-Lint-Problems header.
+func writeGateResponse(w http.ResponseWriter, r *http.Request, pkg *lintPackage) error {
+	problems, _ := pkg.counts()
+	status := 200
+	if problems > 0 {
+		status = 422
+	}
+	w.Header().Set("X-Lint-Problems", strconv.Itoa(problems))
+	if r.Method == "HEAD" {
+		w.WriteHeader(status)
+		return nil
+	}
+	return writeJSONResponse(w, status, pkg)
+}
+
+// problemKey identifies a problem across versions of a package. Line numbers
+// are not part of the key because unrelated changes move problems, and the
+// text is normalized. The source line is left out if either version is
+// compact and has no source lines.
+type problemKey struct {
+	file, text, lineText string
+}
+
+// removeBaselineProblems removes the problems in pkg that are also in
+// baseline. A problem that occurs more often in pkg than in baseline is kept
+// for the extra occurrences.
+func removeBaselineProblems(pkg, baseline *lintPackage) {
+	withLines := !pkg.Compact && !baseline.Compact
+	key := func(f *lintFile, p *lintProblem) problemKey {
+		k := problemKey{file: f.Name, text: p.diffText()}
+		if withLines {
+			k.lineText = p.LineText
+		}
+		return k
+	}
+	counts := make(map[problemKey]int)
+	for _, f := range baseline.Files {
+		for _, p := range f.Problems {
+			counts[key(f, p)]++
+		}
+	}
+	for _, f := range pkg.Files {
+		j := 0
+		for _, p := range f.Problems {
+			k := key(f, p)
+			if counts[k] > 0 {
+				counts[k]--
+				continue
+			}
+			f.Problems[j] = p
+			j++
+		}
+		f.Problems = f.Problems[:j]
+	}
+}
@@ -0,0 +1,95 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package lintapp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+func TestRemoveBaselineProblems(t *testing.T) {
+	baseline := &lintPackage{Files: []*lintFile{{
+		Name: "a.go",
+		Problems: []*lintProblem{
+			{Line: 3, Text: "exported F should have comment", LineText: "func F() {}"},
+			{Line: 7, Text: "don't use underscores", LineText: "var a_b int"},
+		},
+	}}}
+	pkg := &lintPackage{Files: []*lintFile{
+		{
+			Name: "a.go",
+			Problems: []*lintProblem{
+				// Moved by an unrelated change.
+				{Line: 5, Text: "exported F should have comment", LineText: "func F() {}"},
+				{Line: 9, Text: "don't use underscores", LineText: "var a_b int"},
+				// A second occurrence is new.
+				{Line: 10, Text: "don't use underscores", LineText: "var a_b int"},
+			},
+		},
+		{
+			Name:     "b.go",
+			Problems: []*lintProblem{{Line: 3, Text: "exported F should have comment", LineText: "func F() {}"}},
+		},
+	}}
+	removeBaselineProblems(pkg, baseline)
+
+	var got []string
+	for _, f := range pkg.Files {
+		for _, p := range f.Problems {
+			got = append(got, f.Name+":"+p.Text)
+		}
+	}
+	want := []string{"a.go:don't use underscores", "b.go:exported F should have comment"}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("new problems are %q, want %q", got, want)
+	}
+}
+
+func TestRemoveCompactBaselineProblems(t *testing.T) {
+	pkg := &lintPackage{Files: []*lintFile{{Name: "a.go", Problems: []*lintProblem{
+		{Line: 3, Text: "exported func F should have comment or be unexported", LineText: "func F() {}"},
+		{Line: 7, Text: "var x_y should be xY", LineText: "var x_y int"},
+	}}}}
+	baseline := compactPackage(&lintPackage{Files: []*lintFile{{Name: "a.go", Problems: []*lintProblem{
+		{Line: 2, Text: "exported func F should have comment or be unexported", LineText: "func F() {}"},
+	}}}})
+	removeBaselineProblems(pkg, baseline)
+	if n, _ := pkg.counts(); n != 1 || pkg.Files[0].Problems[0].Line != 7 {
+		t.Errorf("after removing a compact baseline, problems = %+v, want the one on line 7", pkg.Files[0].Problems)
+	}
+}
+
+func TestWriteGateResponse(t *testing.T) {
+	for _, method := range []string{"GET", "HEAD"} {
+		for _, n := range []int{0, 2} {
+			pkg := &lintPackage{Files: []*lintFile{{Name: "a.go"}}}
+			for i := 0; i < n; i++ {
+				pkg.Files[0].Problems = append(pkg.Files[0].Problems, &lintProblem{Text: "x"})
+			}
+			r, _ := http.NewRequest(method, "/-/against-baseline", nil)
+			w := httptest.NewRecorder()
+			if err := writeGateResponse(w, r, pkg); err != nil {
+				t.Fatal(err)
+			}
+			wantStatus := 200
+			if n > 0 {
+				wantStatus = 422
+			}
+			if w.Code != wantStatus {
+				t.Errorf("%s with %d problems: status %d, want %d", method, n, w.Code, wantStatus)
+			}
+			if got := w.Header().Get("X-Lint-Problems"); got != strconv.Itoa(n) {
+				t.Errorf("%s with %d problems: X-Lint-Problems = %q", method, n, got)
+			}
+			if empty := w.Body.Len() == 0; empty != (method == "HEAD") {
+				t.Errorf("%s with %d problems: body %q", method, n, w.Body)
+			}
+		}
+	}
+}
@@ -0,0 +1,242 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package lintapp
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/net/context"
+	"google.golang.org/appengine"
+	"google.golang.org/appengine/log"
+)
+
+// maxBatchSize is the maximum number of packages in a batch request.
+var maxBatchSize = 20
+
+// packageBatch collects lint results to store them together with
+// putPackages, instead of one write per package.
+type packageBatch struct {
+	keys []string
+	pkgs []*lintPackage
+}
+
+func (b *packageBatch) add(key string, pkg *lintPackage) {
+	b.keys = append(b.keys, key)
+	b.pkgs = append(b.pkgs, pkg)
+}
+
+// flush stores the collected results and returns the errors storing them by
+// key. If storing the results together fails, they are stored one by one, so
+// that one bad result does not fail the others.
+func (b *packageBatch) flush(c context.Context) map[string]error {
+	keys, pkgs := b.keys, b.pkgs
+	b.keys, b.pkgs = nil, nil
+	if len(keys) == 0 {
+		return nil
+	}
+	err := putPackages(c, keys, pkgs)
+	if err == nil {
+		return nil
+	}
+	log.Warningf(c, "Storing %d batch results together: %v", len(keys), err)
+	errs := make(map[string]error)
+	for i, key := range keys {
+		if err := putPackage(c, key, pkgs[i]); err != nil {
+			errs[key] = err
+		}
+	}
+	return errs
+}
+
+// batchResult is the result for one package of a batch request.
+type batchResult struct {
+	Path    string        `json:"path"`
+	Result  *lintPackage  `json:"result,omitempty"`
+	Summary *batchSummary `json:"summary,omitempty"`
+	Error   string        `json:"error,omitempty"`
+
+	// key is the key of a new result added to the batch.
+	key string
+}
+
+// batchSummary is the result for one package of a batch request with the
+// summary format.
+type batchSummary struct {
+	Problems int     `json:"problems"`
+	Files    int     `json:"files"`
+	Score    float64 `json:"score"`
+}
+
+// batchRequest is the JSON body of a batch request. The options apply to
+// every package of the batch.
+type batchRequest struct {
+	Paths []string `json:"paths"`
+
+	// MinConfidence is the minimum confidence of the problems. The default
+	// is the minConfidence parameter or the default for each path.
+	MinConfidence *float64 `json:"minConfidence"`
+
+	// Exclude lists patterns of files to leave out, as excludeFiles does.
+	Exclude []string `json:"exclude"`
+
+	// Format is "full" for all problems, the default, or "summary" for the
+	// problem and file counts and the score.
+	Format string `json:"format"`
+}
+
+// maxBatchBody is the maximum size of a JSON batch request body.
+const maxBatchBody = 1 << 20
+
+// parseBatchRequest returns the batch request of r, given as a JSON body or
+// as path and excludeFiles form parameters.
+func parseBatchRequest(r *http.Request) (*batchRequest, error) {
+	var req batchRequest
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		if err := json.NewDecoder(io.LimitReader(r.Body, maxBatchBody)).Decode(&req); err != nil {
+			return nil, &appError{Status: 400, Message: "Bad request.", Detail: err.Error()}
+		}
+	} else {
+		if err := r.ParseForm(); err != nil {
+			return nil, &appError{Status: 400, Message: "Bad request.", Detail: err.Error()}
+		}
+		req.Paths = r.PostForm["path"]
+		exclude, err := parseExcludeFiles(r)
+		if err != nil {
+			return nil, err
+		}
+		req.Exclude = exclude
+	}
+	if len(req.Paths) == 0 {
+		return nil, &appError{Status: 400, Message: "No paths."}
+	}
+	if len(req.Paths) > maxBatchSize {
+		return nil, &appError{Status: 400, Message: "Too many paths."}
+	}
+	if c := req.MinConfidence; c != nil && (*c < 0 || *c > 1) {
+		return nil, &appError{Status: 400, Message: "Bad minConfidence.", Detail: "The confidence must be between 0 and 1."}
+	}
+	for _, p := range req.Exclude {
+		if _, err := filepath.Match(p, ""); err != nil {
+			return nil, &appError{Status: 400, Message: "Bad exclude pattern.", Detail: p}
+		}
+	}
+	switch req.Format {
+	case "":
+		req.Format = "full"
+	case "full", "summary":
+	default:
+		return nil, &appError{Status: 400, Message: "Bad format.", Detail: `The format must be "full" or "summary".`}
+	}
+	return &req, nil
+}
+
+// filter applies the options of the request to the result for one package.
+func (req *batchRequest) filter(r *http.Request, pkg *lintPackage) {
+	lo := minConfidence(r, pkg.Path)
+	if req.MinConfidence != nil {
+		lo = *req.MinConfidence
+	}
+	separateParseErrors(r, pkg)
+	filterConfidenceRange(pkg, lo, maxConfidence(r))
+	excludeFiles(pkg, req.Exclude)
+}
+
+// lintBatchPackage returns the result for importPath filtered by req,
+// linting the package if it is not stored. If b is not nil, a new result is
+// added to b instead of stored.
+func lintBatchPackage(r *http.Request, req *batchRequest, importPath string, opts lintOptions, b *packageBatch) *batchResult {
+	res := &batchResult{Path: importPath}
+	if err := checkPathLimits(importPath); err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	if !fetcher.ValidPath(importPath) {
+		res.Error = "bad path"
+		return res
+	}
+	pkg, err := getPackage(appengine.NewContext(r), opts.key(importPath))
+	if pkg == nil && err == nil {
+		pkg, err = runLintBatch(r, importPath, opts, b)
+		if err == nil && b != nil {
+			res.key = opts.key(pkg.Path)
+		}
+	}
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	req.filter(r, pkg)
+	if req.Format == "summary" {
+		problems, files := pkg.counts()
+		res.Summary = &batchSummary{Problems: problems, Files: files, Score: pkg.Score}
+		return res
+	}
+	if r.FormValue("fullPath") == "1" {
+		setFullNames(pkg)
+	}
+	res.Result = pkg
+	return res
+}
+
+// serveBatch lints the packages given by a JSON batchRequest body or by the
+// path parameters of a POST request. The response is a JSON array of
+// results, or with format=ndjson, one JSON result per line written as each
+// package finishes. The go1 runtime of App Engine buffers responses, so
+// there the lines are only sent when the whole batch is done; each result
+// is still stored as soon as its package is linted.
+func serveBatch(w http.ResponseWriter, r *http.Request) error {
+	if r.Method != "POST" {
+		return &appError{Status: 405}
+	}
+	req, err := parseBatchRequest(r)
+	if err != nil {
+		return err
+	}
+	paths := req.Paths
+	opts, err := parseLintOptions(r)
+	if err != nil {
+		return err
+	}
+
+	if r.FormValue("format") != "ndjson" {
+		// The response is written after all packages are linted, so the
+		// new results are stored together.
+		var b packageBatch
+		results := make([]*batchResult, len(paths))
+		for i, importPath := range paths {
+			results[i] = lintBatchPackage(r, req, importPath, opts, &b)
+		}
+		errs := b.flush(appengine.NewContext(r))
+		for _, res := range results {
+			if err := errs[res.key]; err != nil {
+				res.Result, res.Summary = nil, nil
+				res.Error = "storing result: " + err.Error()
+			}
+		}
+		return writeJSONResponse(w, 200, results)
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson; charset=utf-8")
+	w.WriteHeader(200)
+	flusher, _ := w.(http.Flusher)
+	enc := json.NewEncoder(w)
+	for _, importPath := range paths {
+		if err := enc.Encode(lintBatchPackage(r, req, importPath, opts, nil)); err != nil {
+			// The response has started; the client sees a truncated stream.
+			return nil
+		}
+		if flusher != nil {
+			// Flushing only has an effect outside App Engine.
+			flusher.Flush()
+		}
+	}
+	return nil
+}
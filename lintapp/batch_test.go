@@ -0,0 +1,126 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package lintapp
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/context"
+	"google.golang.org/appengine"
+	"google.golang.org/appengine/aetest"
+)
+
+var parseBatchRequestTests = []struct {
+	contentType string
+	body        string
+	want        *batchRequest
+}{
+	{
+		"application/json",
+		`{"paths": ["example.com/a", "example.com/b"], "minConfidence": 0.5, "exclude": ["*_test.go"], "format": "summary"}`,
+		&batchRequest{Paths: []string{"example.com/a", "example.com/b"}, MinConfidence: newFloat(0.5), Exclude: []string{"*_test.go"}, Format: "summary"},
+	},
+	{
+		"application/json; charset=utf-8",
+		`{"paths": ["example.com/a"]}`,
+		&batchRequest{Paths: []string{"example.com/a"}, Format: "full"},
+	},
+	{
+		"application/x-www-form-urlencoded",
+		"path=example.com/a&path=example.com/b&excludeFiles=*_test.go",
+		&batchRequest{Paths: []string{"example.com/a", "example.com/b"}, Exclude: []string{"*_test.go"}, Format: "full"},
+	},
+	{"application/json", `{"paths": []}`, nil},
+	{"application/json", `{"paths": ["example.com/a"], "minConfidence": 2}`, nil},
+	{"application/json", `{"paths": ["example.com/a"], "exclude": ["["]}`, nil},
+	{"application/json", `{"paths": ["example.com/a"], "format": "xml"}`, nil},
+	{"application/json", `{"paths": `, nil},
+	{"application/x-www-form-urlencoded", "", nil},
+}
+
+func newFloat(f float64) *float64 { return &f }
+
+func TestParseBatchRequest(t *testing.T) {
+	for _, tt := range parseBatchRequestTests {
+		r, _ := http.NewRequest("POST", "/-/batch", strings.NewReader(tt.body))
+		r.Header.Set("Content-Type", tt.contentType)
+		req, err := parseBatchRequest(r)
+		if tt.want == nil {
+			if err == nil {
+				t.Errorf("parseBatchRequest(%q) returned nil error", tt.body)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseBatchRequest(%q) returned %v", tt.body, err)
+			continue
+		}
+		if !reflect.DeepEqual(req, tt.want) {
+			t.Errorf("parseBatchRequest(%q) = %+v, want %+v", tt.body, req, tt.want)
+		}
+	}
+}
+
+func TestBatchRequestFilter(t *testing.T) {
+	req := &batchRequest{MinConfidence: newFloat(0.5), Exclude: []string{"*_test.go"}}
+	pkg := &lintPackage{Path: "example.com/a", Files: []*lintFile{
+		{Name: "a.go", Problems: []*lintProblem{{Confidence: 0.2}, {Confidence: 0.6}}},
+		{Name: "a_test.go", Problems: []*lintProblem{{Confidence: 1}}},
+	}}
+	r, _ := http.NewRequest("POST", "/-/batch", nil)
+	req.filter(r, pkg)
+	if problems, files := pkg.counts(); problems != 1 || files != 1 {
+		t.Errorf("after filter, counts = %d, %d; want 1, 1", problems, files)
+	}
+}
+
+// failingStore is a memoryStore that fails PutMulti and Put for one key.
+type failingStore struct {
+	*memoryStore
+	bad string
+}
+
+func (s failingStore) Put(c context.Context, key string, pkg *lintPackage) error {
+	if key == s.bad {
+		return errors.New("too large")
+	}
+	return s.memoryStore.Put(c, key, pkg)
+}
+
+func (s failingStore) PutMulti(c context.Context, keys []string, pkgs []*lintPackage) error {
+	return errors.New("commit too large")
+}
+
+func TestPackageBatchFlushErrors(t *testing.T) {
+	i, err := aetest.NewInstance(nil)
+	if err != nil {
+		t.Skipf("App Engine development server not available: %v", err)
+	}
+	defer i.Close()
+	r, err := i.NewRequest("POST", "/-/batch", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := appengine.NewContext(r)
+
+	defer useFakeFetcher(nil)()
+	store = failingStore{newMemoryStore(), "example.com/b"}
+	var b packageBatch
+	b.add("example.com/a", &lintPackage{Path: "example.com/a"})
+	b.add("example.com/b", &lintPackage{Path: "example.com/b"})
+	errs := b.flush(c)
+	if len(errs) != 1 || errs["example.com/b"] == nil {
+		t.Errorf("flush returned %v, want an error for example.com/b only", errs)
+	}
+	if pkg, _ := store.Get(c, "example.com/a"); pkg == nil {
+		t.Error("example.com/a not stored after the batch write failed")
+	}
+}
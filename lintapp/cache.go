@@ -0,0 +1,125 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package lintapp
+
+import (
+	"container/list"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// cachedPackageTTL is how long a package is served from the cache of an
+// instance. Results stored by other instances, such as by refresh tasks, are
+// not seen before the cached package expires.
+var cachedPackageTTL = time.Minute
+
+// packageCache is a least recently used cache of decoded packages on the
+// current instance. Callers get and add copies of packages, so the cached
+// values are never modified by request handling. Entries expire after ttl.
+type packageCache struct {
+	mu    sync.Mutex
+	max   int
+	ttl   time.Duration
+	ll    *list.List
+	items map[string]*list.Element
+}
+
+type packageCacheEntry struct {
+	key   string
+	pkg   *lintPackage
+	added time.Time
+}
+
+func newPackageCache(max int, ttl time.Duration) *packageCache {
+	return &packageCache{max: max, ttl: ttl, ll: list.New(), items: make(map[string]*list.Element)}
+}
+
+// cacheKey includes the storage version so that a deploy with a new version
+// does not serve packages decoded by the previous one.
+func cacheKey(key string) string {
+	return strconv.Itoa(version) + ":" + key
+}
+
+func (pc *packageCache) get(key string) *lintPackage {
+	if pc.max <= 0 {
+		return nil
+	}
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	e, ok := pc.items[cacheKey(key)]
+	if !ok {
+		return nil
+	}
+	entry := e.Value.(*packageCacheEntry)
+	if now().Sub(entry.added) > pc.ttl {
+		pc.ll.Remove(e)
+		delete(pc.items, entry.key)
+		return nil
+	}
+	pc.ll.MoveToFront(e)
+	return entry.pkg.clone()
+}
+
+func (pc *packageCache) add(key string, pkg *lintPackage) {
+	if pc.max <= 0 {
+		return
+	}
+	pkg = pkg.clone()
+	key = cacheKey(key)
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	if e, ok := pc.items[key]; ok {
+		pc.ll.MoveToFront(e)
+		entry := e.Value.(*packageCacheEntry)
+		entry.pkg, entry.added = pkg, now()
+		return
+	}
+	pc.items[key] = pc.ll.PushFront(&packageCacheEntry{key: key, pkg: pkg, added: now()})
+	for pc.ll.Len() > pc.max {
+		e := pc.ll.Back()
+		pc.ll.Remove(e)
+		delete(pc.items, e.Value.(*packageCacheEntry).key)
+	}
+}
+
+func (pc *packageCache) remove(key string) {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	if e, ok := pc.items[cacheKey(key)]; ok {
+		pc.ll.Remove(e)
+		delete(pc.items, cacheKey(key))
+	}
+}
+
+// clone returns a deep copy of pkg.
+func (pkg *lintPackage) clone() *lintPackage {
+	c := *pkg
+	c.Generated = append([]string(nil), pkg.Generated...)
+	c.Unfetched = append([]string(nil), pkg.Unfetched...)
+	c.Packages = append([]string(nil), pkg.Packages...)
+	c.Unanalyzed = append([]unanalyzedFile(nil), pkg.Unanalyzed...)
+	if pkg.Hashes != nil {
+		c.Hashes = make(map[string]string, len(pkg.Hashes))
+		for name, h := range pkg.Hashes {
+			c.Hashes[name] = h
+		}
+	}
+	c.Files = make([]*lintFile, len(pkg.Files))
+	for i, f := range pkg.Files {
+		cf := *f
+		cf.Problems = make([]*lintProblem, len(f.Problems))
+		for j, p := range f.Problems {
+			cp := *p
+			cp.Before = append([]string(nil), p.Before...)
+			cp.After = append([]string(nil), p.After...)
+			cf.Problems[j] = &cp
+		}
+		c.Files[i] = &cf
+	}
+	return &c
+}
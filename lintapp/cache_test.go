@@ -0,0 +1,56 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package lintapp
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPackageCacheTTL(t *testing.T) {
+	defer func(old func() time.Time) { now = old }(now)
+	t0 := time.Date(2016, 5, 1, 12, 0, 0, 0, time.UTC)
+	now = func() time.Time { return t0 }
+
+	pc := newPackageCache(10, time.Minute)
+	pc.add("example.com/a", &lintPackage{Path: "example.com/a"})
+	now = func() time.Time { return t0.Add(time.Minute) }
+	if pkg := pc.get("example.com/a"); pkg == nil || pkg.Path != "example.com/a" {
+		t.Errorf("get before the TTL returned %+v", pkg)
+	}
+	now = func() time.Time { return t0.Add(time.Minute + time.Second) }
+	if pkg := pc.get("example.com/a"); pkg != nil {
+		t.Errorf("get after the TTL returned %+v, want nil", pkg)
+	}
+
+	pc.add("example.com/a", &lintPackage{Path: "example.com/a"})
+	if pkg := pc.get("example.com/a"); pkg == nil {
+		t.Error("get after adding the package again returned nil")
+	}
+}
+
+func TestClone(t *testing.T) {
+	pkg := &lintPackage{
+		Packages: []string{"a", "b"},
+		Hashes:   map[string]string{"a.go": "1"},
+		Files: []*lintFile{{Name: "a.go", Problems: []*lintProblem{
+			{Text: "p", Before: []string{"x"}, After: []string{"y"}},
+		}}},
+	}
+	c := pkg.clone()
+	c.Packages[0] = "changed"
+	c.Hashes["a.go"] = "changed"
+	p := c.Files[0].Problems[0]
+	p.Text = "changed"
+	p.Before[0] = "changed"
+	p.After[0] = "changed"
+
+	p = pkg.Files[0].Problems[0]
+	if pkg.Packages[0] != "a" || pkg.Hashes["a.go"] != "1" || p.Text != "p" || p.Before[0] != "x" || p.After[0] != "y" {
+		t.Errorf("changing the clone changed the original: %+v, %+v", pkg, p)
+	}
+}
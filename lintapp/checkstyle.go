@@ -0,0 +1,89 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package lintapp
+
+import (
+	"bytes"
+	"encoding/xml"
+	"net/http"
+	"strconv"
+)
+
+// The types below are the Checkstyle XML report format read by CI servers,
+// such as the Jenkins Warnings plugin.
+
+type checkstyleReport struct {
+	XMLName xml.Name         `xml:"checkstyle"`
+	Version string           `xml:"version,attr"`
+	Files   []checkstyleFile `xml:"file"`
+}
+
+type checkstyleFile struct {
+	Name   string            `xml:"name,attr"`
+	Errors []checkstyleError `xml:"error"`
+}
+
+type checkstyleError struct {
+	Line     int    `xml:"line,attr"`
+	Column   int    `xml:"column,attr,omitempty"`
+	Severity string `xml:"severity,attr"`
+	Message  string `xml:"message,attr"`
+	Source   string `xml:"source,attr"`
+}
+
+// checkstyleSeverity maps a problem to a Checkstyle severity. Lint errors
+// are errors, and problems are warnings or info by confidence.
+func checkstyleSeverity(p *lintProblem) string {
+	switch {
+	case p.Line == 0:
+		return "error"
+	case p.Confidence >= 0.8:
+		return "warning"
+	default:
+		return "info"
+	}
+}
+
+// checkstyleFromPackage converts the problems in pkg to a Checkstyle report.
+// The source of a problem is its rule ID, or golint if it has none.
+func checkstyleFromPackage(pkg *lintPackage) *checkstyleReport {
+	report := &checkstyleReport{Version: "4.3"}
+	for _, f := range pkg.Files {
+		cf := checkstyleFile{Name: f.Name}
+		for _, p := range f.Problems {
+			source := p.RuleID
+			if source == "" {
+				source = "golint"
+			}
+			cf.Errors = append(cf.Errors, checkstyleError{
+				Line:     p.Line,
+				Column:   p.Column,
+				Severity: checkstyleSeverity(p),
+				Message:  p.Text,
+				Source:   source,
+			})
+		}
+		report.Files = append(report.Files, cf)
+	}
+	return report
+}
+
+func writeCheckstyleResponse(w http.ResponseWriter, pkg *lintPackage) error {
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	enc := xml.NewEncoder(&buf)
+	enc.Indent("", "  ")
+	if err := enc.Encode(checkstyleFromPackage(pkg)); err != nil {
+		return err
+	}
+	buf.WriteByte('\n')
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+	w.WriteHeader(200)
+	_, err := w.Write(buf.Bytes())
+	return err
+}
@@ -0,0 +1,42 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package lintapp
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWriteCheckstyleResponse(t *testing.T) {
+	pkg := &lintPackage{Files: []*lintFile{{
+		Name: "sub/a.go",
+		Problems: []*lintProblem{
+			{Line: 3, Column: 6, Text: `exported F should have comment or be unexported`, Confidence: 1, RuleID: "exported-comment"},
+			{Line: 5, Column: 2, Text: `don't use "x"`, Confidence: 0.3},
+			{Text: "parse error"},
+		},
+	}}}
+	w := httptest.NewRecorder()
+	if err := writeCheckstyleResponse(w, pkg); err != nil {
+		t.Fatal(err)
+	}
+	const want = `<?xml version="1.0" encoding="UTF-8"?>
+<checkstyle version="4.3">
+  <file name="sub/a.go">
+    <error line="3" column="6" severity="warning" message="exported F should have comment or be unexported" source="exported-comment"></error>
+    <error line="5" column="2" severity="info" message="don&#39;t use &#34;x&#34;" source="golint"></error>
+    <error line="0" severity="error" message="parse error" source="golint"></error>
+  </file>
+</checkstyle>
+`
+	if got := w.Body.String(); got != want {
+		t.Errorf("body =\n%s\nwant\n%s", got, want)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/xml; charset=utf-8" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+}
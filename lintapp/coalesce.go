@@ -0,0 +1,62 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package lintapp
+
+import (
+	"errors"
+	"sync"
+)
+
+// lintGroup coalesces concurrent lint runs of the same package on the
+// instance, in the manner of golang.org/x/sync/singleflight, so that a burst
+// of requests for an uncached package fetches and lints it once.
+type lintGroup struct {
+	mu    sync.Mutex
+	calls map[string]*lintCall
+}
+
+type lintCall struct {
+	wg  sync.WaitGroup
+	pkg *lintPackage
+	err error
+}
+
+// errLintPanicked is returned to the callers waiting for a lint run that
+// panicked.
+var errLintPanicked = errors.New("lint run panicked")
+
+// do calls fn and returns its results, unless a call with the same key is in
+// progress, in which case it waits for that call and returns its results.
+// The returned package is shared between the callers and must be copied
+// before it is modified.
+func (g *lintGroup) do(key string, fn func() (*lintPackage, error)) (*lintPackage, error) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*lintCall)
+	}
+	if call, ok := g.calls[key]; ok {
+		g.mu.Unlock()
+		call.wg.Wait()
+		return call.pkg, call.err
+	}
+	call := &lintCall{}
+	call.wg.Add(1)
+	g.calls[key] = call
+	g.mu.Unlock()
+
+	// If fn panics, the waiting callers get errLintPanicked and later calls
+	// run fn again.
+	call.err = errLintPanicked
+	defer func() {
+		g.mu.Lock()
+		delete(g.calls, key)
+		g.mu.Unlock()
+		call.wg.Done()
+	}()
+	call.pkg, call.err = fn()
+	return call.pkg, call.err
+}
@@ -0,0 +1,101 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package lintapp
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLintGroup(t *testing.T) {
+	var g lintGroup
+	var calls int32
+	release := make(chan struct{})
+	fn := func() (*lintPackage, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return &lintPackage{Path: "example.com/a"}, nil
+	}
+
+	const n = 10
+	var wg sync.WaitGroup
+	results := make([]*lintPackage, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], _ = g.do("example.com/a", fn)
+		}(i)
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if calls != 1 {
+		t.Errorf("fn called %d times, want 1", calls)
+	}
+	for i, pkg := range results {
+		if pkg == nil || pkg.Path != "example.com/a" {
+			t.Errorf("result %d = %+v, want example.com/a", i, pkg)
+		}
+	}
+
+	// A later call runs fn again.
+	g.do("example.com/a", fn)
+	if calls != 2 {
+		t.Errorf("fn called %d times after the first call finished, want 2", calls)
+	}
+}
+
+func TestLintGroupPanic(t *testing.T) {
+	var g lintGroup
+	started := make(chan struct{})
+	release := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer func() {
+			recover()
+			close(done)
+		}()
+		g.do("example.com/a", func() (*lintPackage, error) {
+			close(started)
+			<-release
+			panic("boom")
+		})
+	}()
+	<-started
+
+	waited := make(chan error, 1)
+	go func() {
+		_, err := g.do("example.com/a", func() (*lintPackage, error) {
+			t.Error("waiting caller ran fn")
+			return nil, nil
+		})
+		waited <- err
+	}()
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	<-done
+
+	select {
+	case err := <-waited:
+		if err != errLintPanicked {
+			t.Errorf("waiting caller got %v, want errLintPanicked", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("waiting caller blocked after the panic")
+	}
+
+	pkg, err := g.do("example.com/a", func() (*lintPackage, error) {
+		return &lintPackage{Path: "example.com/a"}, nil
+	})
+	if err != nil || pkg == nil {
+		t.Errorf("call after the panic returned %v, %v", pkg, err)
+	}
+}
@@ -0,0 +1,107 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package lintapp
+
+import (
+	"net/http"
+	"sort"
+
+	"google.golang.org/appengine"
+)
+
+var compareTemplate = parseTemplate("common.html", "compare.html")
+
+// compareView is the data for the compare template and the JSON response of
+// the compare endpoint.
+type compareView struct {
+	A, B  *compareSide
+	Rules []*compareRule
+}
+
+// compareSide summarizes one of the compared packages.
+type compareSide struct {
+	Path     string
+	URL      string `json:"-"`
+	Problems int
+	Files    int
+	Score    float64
+}
+
+// compareRule holds the number of problems for a rule in each package.
+type compareRule struct {
+	ID   string
+	A, B int
+}
+
+type byRuleID []*compareRule
+
+func (s byRuleID) Len() int           { return len(s) }
+func (s byRuleID) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s byRuleID) Less(i, j int) bool { return s[i].ID < s[j].ID }
+
+// serveCompare shows the problem counts of the packages given by the a and b
+// parameters side by side, in total and by rule. The packages are linted with
+// the same options and confidence filter. Stored results are used when
+// current.
+func serveCompare(w http.ResponseWriter, r *http.Request) error {
+	opts, err := parseLintOptions(r)
+	if err != nil {
+		return err
+	}
+	var pkgs [2]*lintPackage
+	for i, param := range []string{"a", "b"} {
+		importPath := r.FormValue(param)
+		if importPath == "" {
+			return &appError{Status: 400, Message: "Missing " + param + " parameter."}
+		}
+		if err := checkPathLimits(importPath); err != nil {
+			return err
+		}
+		if !fetcher.ValidPath(importPath) {
+			return &appError{Status: 400, Message: "Invalid " + param + " parameter."}
+		}
+		pkg, err := getPackage(appengine.NewContext(r), opts.key(importPath))
+		if pkg == nil && err == nil {
+			pkg, err = runLint(r, importPath, opts)
+		}
+		if err != nil {
+			return err
+		}
+		filterByConfidence(r, pkg)
+		pkgs[i] = pkg
+	}
+	v := compare(pkgs[0], pkgs[1])
+	if negotiateFormat(r) == "json" {
+		return writeJSONResponse(w, 200, v)
+	}
+	return writeResponse(w, r, 200, compareTemplate, v)
+}
+
+// compare returns the comparison of packages a and b.
+func compare(a, b *lintPackage) *compareView {
+	side := func(pkg *lintPackage) *compareSide {
+		problems, files := pkg.counts()
+		return &compareSide{Path: pkg.Path, URL: pkg.PageURL(""), Problems: problems, Files: files, Score: pkg.Score}
+	}
+	v := &compareView{A: side(a), B: side(b)}
+	rules := make(map[string]*compareRule)
+	get := func(id string) *compareRule {
+		if rules[id] == nil {
+			rules[id] = &compareRule{ID: id}
+			v.Rules = append(v.Rules, rules[id])
+		}
+		return rules[id]
+	}
+	for id, n := range ruleHistogram(a) {
+		get(id).A = n
+	}
+	for id, n := range ruleHistogram(b) {
+		get(id).B = n
+	}
+	sort.Sort(byRuleID(v.Rules))
+	return v
+}
@@ -0,0 +1,34 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package lintapp
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCompare(t *testing.T) {
+	a := &lintPackage{Path: "example.com/old", Score: 50, Files: []*lintFile{
+		{Name: "a.go", Problems: []*lintProblem{{RuleID: "exported-comment"}, {RuleID: "exported-comment"}}},
+		{Name: "b.go", Problems: []*lintProblem{{RuleID: "receiver-name"}}},
+	}}
+	b := &lintPackage{Path: "example.com/new", Score: 90, Files: []*lintFile{
+		{Name: "a.go", Problems: []*lintProblem{{RuleID: "exported-comment"}, {}}},
+	}}
+	want := &compareView{
+		A: &compareSide{Path: "example.com/old", URL: "/example.com/old", Problems: 3, Files: 2, Score: 50},
+		B: &compareSide{Path: "example.com/new", URL: "/example.com/new", Problems: 2, Files: 1, Score: 90},
+		Rules: []*compareRule{
+			{ID: "exported-comment", A: 2, B: 1},
+			{ID: otherRuleID, A: 0, B: 1},
+			{ID: "receiver-name", A: 1, B: 0},
+		},
+	}
+	if got := compare(a, b); !reflect.DeepEqual(got, want) {
+		t.Errorf("compare = %+v, want %+v", got, want)
+	}
+}
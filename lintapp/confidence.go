@@ -0,0 +1,158 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package lintapp
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// defaultMinConfidence is the minimum confidence of the problems shown when
+// neither the request nor a path override specifies one.
+const defaultMinConfidence = 0.8
+
+// confidenceOverrides maps import path prefixes to the default minimum
+// confidence for packages below the prefix.
+var confidenceOverrides map[string]float64
+
+// parseConfidenceOverrides parses a comma separated list of prefix=confidence
+// pairs. Malformed pairs are ignored.
+func parseConfidenceOverrides(s string) map[string]float64 {
+	m := make(map[string]float64)
+	for _, pair := range strings.Split(s, ",") {
+		i := strings.LastIndex(pair, "=")
+		if i < 0 {
+			continue
+		}
+		prefix := strings.Trim(strings.TrimSpace(pair[:i]), "/")
+		confidence, err := strconv.ParseFloat(strings.TrimSpace(pair[i+1:]), 64)
+		if prefix == "" || err != nil {
+			continue
+		}
+		m[prefix] = confidence
+	}
+	return m
+}
+
+// pathMinConfidence returns the default minimum confidence for importPath.
+// The override with the longest prefix matching whole path elements wins.
+func pathMinConfidence(importPath string) float64 {
+	confidence := defaultMinConfidence
+	longest := -1
+	for prefix, c := range confidenceOverrides {
+		if len(prefix) <= longest {
+			continue
+		}
+		if importPath == prefix || strings.HasPrefix(importPath, prefix+"/") {
+			confidence = c
+			longest = len(prefix)
+		}
+	}
+	return confidence
+}
+
+// minConfidence returns the minimum confidence of the problems shown for
+// importPath. The minConfidence request parameter overrides the default for
+// the output format, which overrides the default for the path.
+func minConfidence(r *http.Request, importPath string) float64 {
+	if c, err := strconv.ParseFloat(r.FormValue("minConfidence"), 64); err == nil {
+		return c
+	}
+	if c, ok := formatMinConfidence[negotiateFormat(r)]; ok {
+		return c
+	}
+	return pathMinConfidence(importPath)
+}
+
+// maxConfidence returns the maximum confidence of the problems shown, given by
+// the maxConfidence request parameter. The default is 1.
+func maxConfidence(r *http.Request) float64 {
+	if c, err := strconv.ParseFloat(r.FormValue("maxConfidence"), 64); err == nil {
+		return c
+	}
+	return 1
+}
+
+// filterByConfidence removes the problems with confidence outside the range
+// given by minConfidence and maxConfidence, after separating the parse errors
+// with separateParseErrors.
+func filterByConfidence(r *http.Request, pkg *lintPackage) {
+	separateParseErrors(r, pkg)
+	filterConfidenceRange(pkg, minConfidence(r, pkg.Path), maxConfidence(r))
+}
+
+// filterConfidenceRange removes the problems with confidence outside of
+// [lo, hi]. Parse errors are kept.
+func filterConfidenceRange(pkg *lintPackage, lo, hi float64) {
+	for _, f := range pkg.Files {
+		j := 0
+		for i := range f.Problems {
+			if c := f.Problems[i].Confidence; f.Problems[i].ParseError || lo <= c && c <= hi {
+				f.Problems[j] = f.Problems[i]
+				j++
+			}
+		}
+		f.Problems = f.Problems[:j]
+	}
+}
+
+// markBelowThreshold is like filterByConfidence, except that problems with
+// confidence below minConfidence are kept and marked to be shown collapsed.
+func markBelowThreshold(r *http.Request, pkg *lintPackage) {
+	separateParseErrors(r, pkg)
+	lo, hi := minConfidence(r, pkg.Path), maxConfidence(r)
+	for _, f := range pkg.Files {
+		j := 0
+		for _, p := range f.Problems {
+			if p.ParseError || p.Confidence <= hi {
+				p.BelowThreshold = !p.ParseError && p.Confidence < lo
+				f.Problems[j] = p
+				j++
+			}
+		}
+		f.Problems = f.Problems[:j]
+	}
+}
+
+// maxStars is the number of stars of a problem with confidence 1.
+const maxStars = 5
+
+// starsFn renders a confidence between 0 and 1 as a rating of 1 to maxStars
+// filled stars, rounding to the nearest star.
+func starsFn(confidence float64) string {
+	n := int(confidence*maxStars + 0.5)
+	if n < 1 {
+		n = 1
+	} else if n > maxStars {
+		n = maxStars
+	}
+	return strings.Repeat("★", n) + strings.Repeat("☆", maxStars-n)
+}
+
+// lowConfidence is the confidence below which golint's suggestions are
+// rarely actionable.
+const lowConfidence = 0.5
+
+// warnLowConfidenceCount is the number of problems with confidence below
+// lowConfidence above which package pages advise against the low minimum
+// confidence. Zero disables the advice.
+var warnLowConfidenceCount = 20
+
+// lowConfidenceCount returns the number of problems in pkg with confidence
+// below lowConfidence that are not shown collapsed.
+func lowConfidenceCount(pkg *lintPackage) int {
+	n := 0
+	for _, f := range pkg.Files {
+		for _, p := range f.Problems {
+			if p.Confidence < lowConfidence && !p.BelowThreshold {
+				n++
+			}
+		}
+	}
+	return n
+}
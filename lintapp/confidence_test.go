@@ -0,0 +1,136 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package lintapp
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestPathMinConfidence(t *testing.T) {
+	defer func(m map[string]float64) { confidenceOverrides = m }(confidenceOverrides)
+	confidenceOverrides = parseConfidenceOverrides("example.com/internal=0.5, example.com/internal/legacy/=0.95,bad,x=y")
+
+	tests := []struct {
+		path string
+		want float64
+	}{
+		{"example.com/internal", 0.5},
+		{"example.com/internal/foo", 0.5},
+		{"example.com/internal/legacy/foo", 0.95},
+		{"example.com/internalfoo", defaultMinConfidence},
+		{"github.com/a/b", defaultMinConfidence},
+	}
+	for _, tt := range tests {
+		if got := pathMinConfidence(tt.path); got != tt.want {
+			t.Errorf("pathMinConfidence(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+	if len(confidenceOverrides) != 2 {
+		t.Errorf("parsed %d overrides, want 2", len(confidenceOverrides))
+	}
+
+	r, _ := http.NewRequest("GET", "/example.com/internal?minConfidence=0.2", nil)
+	if got := minConfidence(r, "example.com/internal"); got != 0.2 {
+		t.Errorf("minConfidence with parameter = %v, want 0.2", got)
+	}
+}
+
+func TestFilterByConfidence(t *testing.T) {
+	newPackage := func() *lintPackage {
+		return &lintPackage{Path: "github.com/a/b", Files: []*lintFile{{
+			Name: "b.go",
+			Problems: []*lintProblem{
+				{Text: "a", Confidence: 0.2},
+				{Text: "b", Confidence: 0.5},
+				{Text: "c", Confidence: 0.8},
+				{Text: "d", Confidence: 1},
+			},
+		}}}
+	}
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"", "cd"},
+		{"minConfidence=0.5", "bcd"},
+		{"minConfidence=0.5&maxConfidence=0.8", "bc"},
+		{"maxConfidence=0.9", "c"},
+		{"maxConfidence=x", "cd"},
+		{"format=json", "abcd"},
+		{"format=json&minConfidence=0.8", "cd"},
+		{"format=golint", "cd"},
+		{"format=summary", "cd"},
+		{"format=lsp", "abcd"},
+		{"format=checkstyle", "abcd"},
+		{"format=gha", "abcd"},
+		{"format=histogram", "abcd"},
+	}
+	for _, tt := range tests {
+		r, _ := http.NewRequest("GET", "/github.com/a/b?"+tt.query, nil)
+		pkg := newPackage()
+		filterByConfidence(r, pkg)
+		got := ""
+		for _, p := range pkg.Files[0].Problems {
+			got += p.Text
+		}
+		if got != tt.want {
+			t.Errorf("filterByConfidence with %q kept %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestMarkBelowThreshold(t *testing.T) {
+	r, _ := http.NewRequest("GET", "/github.com/a/b?collapse=1&maxConfidence=0.9", nil)
+	pkg := &lintPackage{Path: "github.com/a/b", Files: []*lintFile{{
+		Name: "b.go",
+		Problems: []*lintProblem{
+			{Text: "a", Confidence: 0.2},
+			{Text: "b", Confidence: 0.8},
+			{Text: "c", Confidence: 1},
+		},
+	}}}
+	markBelowThreshold(r, pkg)
+	problems := pkg.Files[0].Problems
+	if len(problems) != 2 || !problems[0].BelowThreshold || problems[1].BelowThreshold {
+		t.Errorf("marked problems %+v, want a below threshold and b shown", problems)
+	}
+	if c := pkg.Files[0].Collapsed(); len(c) != 1 || c[0].Text != "a" {
+		t.Errorf("Collapsed() = %+v, want [a]", c)
+	}
+}
+
+var starsTests = []struct {
+	confidence float64
+	stars      string
+}{
+	{1, "★★★★★"},
+	{0.9, "★★★★★"},
+	{0.8, "★★★★☆"},
+	{0.6, "★★★☆☆"},
+	{0.2, "★☆☆☆☆"},
+	{0, "★☆☆☆☆"},
+	{1.5, "★★★★★"},
+}
+
+func TestStars(t *testing.T) {
+	for _, tt := range starsTests {
+		if s := starsFn(tt.confidence); s != tt.stars {
+			t.Errorf("starsFn(%v) = %q, want %q", tt.confidence, s, tt.stars)
+		}
+	}
+}
+
+func TestLowConfidenceCount(t *testing.T) {
+	pkg := &lintPackage{Files: []*lintFile{
+		{Name: "a.go", Problems: []*lintProblem{{Confidence: 0.2}, {Confidence: 0.9}, {Confidence: 0.2, BelowThreshold: true}}},
+		{Name: "b.go", Problems: []*lintProblem{{Confidence: 0.3}, {Confidence: 0.5}}},
+	}}
+	if n := lowConfidenceCount(pkg); n != 2 {
+		t.Errorf("lowConfidenceCount = %d, want 2", n)
+	}
+}
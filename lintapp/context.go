@@ -0,0 +1,90 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package lintapp
+
+import (
+	"bytes"
+	"net/http"
+	"strconv"
+)
+
+const (
+	// maxContextLines is the number of source lines stored before and
+	// after each problem line.
+	maxContextLines = 3
+
+	// maxContextBytes limits the total size of the context lines stored for
+	// a package.
+	maxContextBytes = 32 << 10
+)
+
+// addContext sets the source lines before and after each problem in file
+// from the file source in data. Context is added until the budget of bytes is
+// spent.
+func addContext(file *lintFile, data []byte, budget *int) {
+	var lines [][]byte
+	for _, p := range file.Problems {
+		if p.Line == 0 || *budget <= 0 {
+			continue
+		}
+		if lines == nil {
+			lines = bytes.Split(data, []byte("\n"))
+		}
+		if p.Line > len(lines) {
+			continue
+		}
+		i := p.Line - 1
+		start := i - maxContextLines
+		if start < 0 {
+			start = 0
+		}
+		end := i + 1 + maxContextLines
+		if end > len(lines) {
+			end = len(lines)
+		}
+		p.Before = contextLines(lines[start:i], budget)
+		p.After = contextLines(lines[i+1:end], budget)
+	}
+}
+
+func contextLines(lines [][]byte, budget *int) []string {
+	var s []string
+	for _, line := range lines {
+		text, _ := truncateLineText(string(bytes.TrimRight(line, "\r")), 0, maxLineText)
+		*budget -= len(text)
+		s = append(s, text)
+	}
+	return s
+}
+
+// contextParam returns the number of context lines requested with the
+// context parameter, at most maxContextLines.
+func contextParam(r *http.Request) int {
+	n, err := strconv.Atoi(r.FormValue("context"))
+	switch {
+	case err != nil || n < 0:
+		return 0
+	case n > maxContextLines:
+		return maxContextLines
+	}
+	return n
+}
+
+// trimContext limits the context of the problems in pkg to n lines before and
+// after the problem line.
+func trimContext(pkg *lintPackage, n int) {
+	for _, f := range pkg.Files {
+		for _, p := range f.Problems {
+			if len(p.Before) > n {
+				p.Before = p.Before[len(p.Before)-n:]
+			}
+			if len(p.After) > n {
+				p.After = p.After[:n]
+			}
+		}
+	}
+}
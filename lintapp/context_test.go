@@ -0,0 +1,44 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package lintapp
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestContext(t *testing.T) {
+	data := []byte("1\n2\n3\n4\n5\n6\n7\n8\n9")
+	file := &lintFile{Problems: []*lintProblem{{Line: 2}, {Line: 5}, {Line: 9}, {Line: 0}}}
+	budget := maxContextBytes
+	addContext(file, data, &budget)
+
+	want := []struct{ before, after []string }{
+		{[]string{"1"}, []string{"3", "4", "5"}},
+		{[]string{"2", "3", "4"}, []string{"6", "7", "8"}},
+		{[]string{"6", "7", "8"}, nil},
+		{nil, nil},
+	}
+	for i, w := range want {
+		p := file.Problems[i]
+		if !reflect.DeepEqual(p.Before, w.before) || !reflect.DeepEqual(p.After, w.after) {
+			t.Errorf("problem on line %d has context %q, %q; want %q, %q", p.Line, p.Before, p.After, w.before, w.after)
+		}
+	}
+
+	trimContext(&lintPackage{Files: []*lintFile{file}}, 1)
+	if p := file.Problems[1]; !reflect.DeepEqual(p.Before, []string{"4"}) || !reflect.DeepEqual(p.After, []string{"6"}) {
+		t.Errorf("trimmed context is %q, %q; want [4], [6]", p.Before, p.After)
+	}
+
+	file = &lintFile{Problems: []*lintProblem{{Line: 2}, {Line: 5}}}
+	budget = 2
+	addContext(file, data, &budget)
+	if p := file.Problems[1]; p.Before != nil || p.After != nil {
+		t.Errorf("context added after budget was spent: %q, %q", p.Before, p.After)
+	}
+}
@@ -0,0 +1,58 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package lintapp
+
+import (
+	"go/parser"
+	"go/token"
+	"strings"
+)
+
+// deprecatedHosts maps the import path prefixes of hosts that are no longer
+// maintained to the note shown for their packages.
+var deprecatedHosts = map[string]string{
+	"code.google.com/": "Google Code was shut down in 2016. The package may have moved to a new import path.",
+}
+
+// checkDeprecated sets pkg.Deprecated and pkg.DeprecationNote if the package
+// is on a deprecated host or its package documentation has a paragraph
+// starting with "Deprecated:".
+func checkDeprecated(pkg *lintPackage, files []*File) {
+	for prefix, note := range deprecatedHosts {
+		if strings.HasPrefix(pkg.Path, prefix) {
+			pkg.Deprecated = true
+			pkg.DeprecationNote = note
+			return
+		}
+	}
+	for _, f := range files {
+		if !strings.HasSuffix(f.Name, ".go") || strings.HasSuffix(f.Name, "_test.go") {
+			continue
+		}
+		if note, ok := deprecationNote(f.Data); ok {
+			pkg.Deprecated = true
+			pkg.DeprecationNote = note
+			return
+		}
+	}
+}
+
+// deprecationNote returns the text following "Deprecated:" in the paragraph of
+// the package documentation in src that starts with it, with lines joined by
+// spaces.
+func deprecationNote(src []byte) (string, bool) {
+	f, err := parser.ParseFile(token.NewFileSet(), "", src, parser.PackageClauseOnly|parser.ParseComments)
+	if err != nil || f.Doc == nil {
+		return "", false
+	}
+	for _, para := range strings.Split(f.Doc.Text(), "\n\n") {
+		if strings.HasPrefix(para, "Deprecated:") {
+			return strings.Join(strings.Fields(strings.TrimPrefix(para, "Deprecated:")), " "), true
+		}
+	}
+	return "", false
+}
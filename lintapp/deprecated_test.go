@@ -0,0 +1,31 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package lintapp
+
+import "testing"
+
+func TestCheckDeprecated(t *testing.T) {
+	tests := []struct {
+		path       string
+		src        string
+		deprecated bool
+		note       string
+	}{
+		{"github.com/a/b", "// Package b does things.\npackage b\n", false, ""},
+		{"github.com/a/b", "// Package b does things.\n//\n// Deprecated: Use\n// github.com/a/c instead.\npackage b\n", true, "Use github.com/a/c instead."},
+		{"github.com/a/b", "// Deprecated:\npackage b\n", true, ""},
+		{"github.com/a/b", "// Package b does things.\npackage b\n\n// Deprecated: not package documentation.\nfunc F() {}\n", false, ""},
+		{"code.google.com/p/b", "package b\n", true, deprecatedHosts["code.google.com/"]},
+	}
+	for _, tt := range tests {
+		pkg := &lintPackage{Path: tt.path}
+		checkDeprecated(pkg, []*File{{Name: "b.go", Data: []byte(tt.src)}})
+		if pkg.Deprecated != tt.deprecated || pkg.DeprecationNote != tt.note {
+			t.Errorf("checkDeprecated(%q, %q) set %v, %q; want %v, %q", tt.path, tt.src, pkg.Deprecated, pkg.DeprecationNote, tt.deprecated, tt.note)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package lintapp
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"google.golang.org/appengine"
+	"google.golang.org/appengine/log"
+)
+
+// drainTimeout is how long serveStop waits for lint runs in progress. App
+// Engine shuts the instance down 30 seconds after the stop request.
+var drainTimeout = 25 * time.Second
+
+// lintsInFlight tracks the lint runs in progress on the instance.
+var lintsInFlight drainer
+
+// drainer counts operations in progress and waits for them to finish when
+// the instance is stopped. Once draining, no new operations are started.
+type drainer struct {
+	mu       sync.Mutex
+	n        int
+	draining bool
+	idle     chan struct{} // closed when n drops to zero while draining
+}
+
+// start records the start of an operation. It returns false if the drainer
+// is draining, in which case the operation must not be started.
+func (d *drainer) start() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.draining {
+		return false
+	}
+	d.n++
+	return true
+}
+
+// done records the end of an operation started with start.
+func (d *drainer) done() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.n--
+	if d.n == 0 && d.idle != nil {
+		close(d.idle)
+		d.idle = nil
+	}
+}
+
+// drain stops new operations from starting and waits up to timeout for the
+// operations in progress. It returns the number still in progress.
+func (d *drainer) drain(timeout time.Duration) int {
+	d.mu.Lock()
+	d.draining = true
+	if d.n == 0 {
+		d.mu.Unlock()
+		return 0
+	}
+	if d.idle == nil {
+		d.idle = make(chan struct{})
+	}
+	idle := d.idle
+	d.mu.Unlock()
+
+	select {
+	case <-idle:
+	case <-time.After(timeout):
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.n
+}
+
+// serveStop handles the request App Engine sends to manual and basic scaling
+// instances before they are shut down. New lint runs are rejected with
+// errBusy, so that clients retry on another instance, and lint runs in
+// progress are given drainTimeout to finish and store their results. The
+// buffered events are then queued. The request comes from App Engine, so it
+// is not subject to checkAuth.
+func serveStop(w http.ResponseWriter, r *http.Request) {
+	c := appengine.NewContext(r)
+	if n := lintsInFlight.drain(drainTimeout); n > 0 {
+		log.Warningf(c, "Stopping with %d lint runs in progress", n)
+	} else {
+		log.Infof(c, "Stopping with no lint runs in progress")
+	}
+	queueEvents(c, pendingEvents.take())
+	w.WriteHeader(http.StatusOK)
+}
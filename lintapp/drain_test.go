@@ -0,0 +1,42 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package lintapp
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDrainer(t *testing.T) {
+	var d drainer
+	if !d.start() || !d.start() {
+		t.Fatal("start before drain returned false")
+	}
+	go func() {
+		d.done()
+		time.Sleep(10 * time.Millisecond)
+		d.done()
+	}()
+	if n := d.drain(time.Minute); n != 0 {
+		t.Errorf("drain = %d, want 0", n)
+	}
+	if d.start() {
+		t.Error("start after drain returned true")
+	}
+}
+
+func TestDrainerTimeout(t *testing.T) {
+	var d drainer
+	d.start()
+	if n := d.drain(10 * time.Millisecond); n != 1 {
+		t.Errorf("drain = %d, want 1", n)
+	}
+	d.done()
+	if n := d.drain(time.Minute); n != 0 {
+		t.Errorf("drain after done = %d, want 0", n)
+	}
+}
@@ -0,0 +1,228 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package lintapp
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"golang.org/x/net/context"
+	"google.golang.org/appengine/urlfetch"
+
+	"github.com/ReturnPath/gddo/gosrc"
+)
+
+// Directory is the source of a package.
+type Directory struct {
+	// ImportPath is the canonical import path of the package, which
+	// differs from the requested path if the package moved.
+	ImportPath string
+
+	// ProjectRoot is the import path of the repository root.
+	ProjectRoot string
+
+	// BrowseURL is the location of the package on the source host.
+	BrowseURL string
+
+	// LineFmt formats a file URL and line number as the URL of the line.
+	LineFmt string
+
+	Files []*File
+}
+
+// File is a file in a Directory.
+type File struct {
+	// Name is the file name with no directory.
+	Name string
+
+	Data []byte
+
+	// BrowseURL is the location of the file on the source host.
+	BrowseURL string
+}
+
+// Fetcher fetches package sources. Fetch returns a gosrc.NotFoundError if
+// the package does not exist, with Redirect set if it moved, and a
+// *gosrc.RemoteError if the source host fails. Use fetchPackage to follow
+// redirects.
+type Fetcher interface {
+	// Fetch returns the source of the package at revision rev, or at the
+	// default revision if rev is empty. Fetchers that cannot fetch other
+	// revisions return errRevisionUnsupported for a non-empty rev.
+	Fetch(c context.Context, importPath, rev string) (*Directory, error)
+
+	// ValidPath reports whether importPath can be fetched.
+	ValidPath(importPath string) bool
+}
+
+// fetcher is the configured Fetcher.
+var fetcher Fetcher = gosrcFetcher{}
+
+type httpClientKey struct{}
+
+// withHTTPClient returns a context that makes gosrcFetcher use client.
+func withHTTPClient(c context.Context, client *http.Client) context.Context {
+	return context.WithValue(c, httpClientKey{}, client)
+}
+
+// gosrcFetcher fetches packages with gosrc using the HTTP client set with
+// withHTTPClient, or a plain urlfetch client.
+type gosrcFetcher struct{}
+
+// errRevisionUnsupported is returned by Fetch for a revision other than the
+// default one.
+var errRevisionUnsupported = &appError{Status: 400, Message: "Fetching a revision other than the default is not supported."}
+
+// errPullRequestUnsupported is returned for requests with a pr parameter.
+// Linting a pull request head needs Fetch to support revisions.
+var errPullRequestUnsupported = &appError{
+	Status:  400,
+	Message: "Linting a pull request is not supported.",
+	Detail:  "Only the default branch can be fetched. Leave out the pr parameter to lint it.",
+}
+
+// Fetch fetches the default revision only. The last argument of gosrc.Get is
+// the etag of a previous fetch, not a revision.
+func (gosrcFetcher) Fetch(c context.Context, importPath, rev string) (*Directory, error) {
+	if rev != "" {
+		return nil, errRevisionUnsupported
+	}
+	client, _ := c.Value(httpClientKey{}).(*http.Client)
+	if client == nil {
+		client = urlfetch.Client(c)
+	}
+	dir, err := gosrc.Get(client, importPath, "")
+	if err != nil {
+		return nil, err
+	}
+	d := &Directory{
+		ImportPath:  importPath,
+		ProjectRoot: dir.ProjectRoot,
+		BrowseURL:   dir.BrowseURL,
+		LineFmt:     dir.LineFmt,
+	}
+	for _, f := range dir.Files {
+		d.Files = append(d.Files, &File{Name: f.Name, Data: f.Data, BrowseURL: f.BrowseURL})
+	}
+	return d, nil
+}
+
+func (gosrcFetcher) ValidPath(importPath string) bool {
+	return gosrc.IsValidPath(importPath)
+}
+
+// maxRedirects is the number of canonical path redirects fetchPackage
+// follows for a moved repository.
+const maxRedirects = 3
+
+// redirectError is returned by fetchPackage when the redirects of a moved
+// repository form a cycle or exceed maxRedirects.
+type redirectError struct {
+	// paths are the import paths visited, in order.
+	paths []string
+	loop  bool
+}
+
+func (e *redirectError) Error() string {
+	if e.loop {
+		return "redirect loop detected: " + strings.Join(e.paths, " -> ")
+	}
+	return "too many redirects: " + strings.Join(e.paths, " -> ")
+}
+
+// fetchPackage fetches importPath with the configured fetcher, following the
+// redirects of moved repositories or paths with the wrong case. The
+// ImportPath of the returned directory is the canonical path.
+func fetchPackage(c context.Context, importPath, rev string) (*Directory, error) {
+	paths := []string{importPath}
+	for {
+		dir, err := fetcher.Fetch(c, importPath, rev)
+		e, ok := err.(gosrc.NotFoundError)
+		if !ok || e.Redirect == "" {
+			return dir, err
+		}
+		for _, p := range paths {
+			if p == e.Redirect {
+				return nil, &redirectError{paths: append(paths, e.Redirect), loop: true}
+			}
+		}
+		paths = append(paths, e.Redirect)
+		if len(paths) > maxRedirects+1 {
+			return nil, &redirectError{paths: paths}
+		}
+		importPath = e.Redirect
+	}
+}
+
+// noPackageError is returned by runLint for a directory without Go files,
+// such as a repository root with the packages in subdirectories.
+type noPackageError struct {
+	importPath string
+}
+
+func (e noPackageError) Error() string {
+	return e.importPath + " has no Go files"
+}
+
+// isNotFound reports whether err is a gosrc.NotFoundError, a noPackageError
+// or a redirectError, for which retrying a lint run does not help.
+func isNotFound(err error) bool {
+	switch err.(type) {
+	case gosrc.NotFoundError, noPackageError, *redirectError:
+		return true
+	}
+	return false
+}
+
+// hasGoFiles reports whether files includes a Go source file.
+func hasGoFiles(files []*File) bool {
+	for _, f := range files {
+		if strings.HasSuffix(f.Name, ".go") {
+			return true
+		}
+	}
+	return false
+}
+
+// lineFmtOverrides maps hosts to the LineFmt of their packages, for hosts on
+// which gosrc does not set a LineFmt or sets a wrong one.
+var lineFmtOverrides map[string]string
+
+// parseLineFmtOverrides parses a comma separated list of host=format pairs.
+// The format must take the file URL and the line number, as in %s#L%d.
+// Malformed pairs are ignored.
+func parseLineFmtOverrides(s string) map[string]string {
+	m := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		i := strings.Index(pair, "=")
+		if i < 0 {
+			continue
+		}
+		host := strings.ToLower(strings.TrimSpace(pair[:i]))
+		format := strings.TrimSpace(pair[i+1:])
+		if host == "" || strings.Contains(fmt.Sprintf(format, "", 0), "%!") {
+			continue
+		}
+		m[host] = format
+	}
+	return m
+}
+
+// lineFmt returns the LineFmt of the package with the given import path:
+// the override for its host, if any, or else the LineFmt set by the
+// fetcher.
+func lineFmt(importPath, fetched string) string {
+	host := importPath
+	if i := strings.Index(host, "/"); i >= 0 {
+		host = host[:i]
+	}
+	if format, ok := lineFmtOverrides[host]; ok {
+		return format
+	}
+	return fetched
+}
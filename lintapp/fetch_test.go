@@ -0,0 +1,247 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package lintapp
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+	"google.golang.org/appengine"
+	"google.golang.org/appengine/aetest"
+
+	"github.com/ReturnPath/gddo/gosrc"
+)
+
+// fakeFetcher serves directories from memory.
+type fakeFetcher map[string]*Directory
+
+func (f fakeFetcher) Fetch(c context.Context, importPath, rev string) (*Directory, error) {
+	dir := f[importPath]
+	if dir == nil {
+		return nil, gosrc.NotFoundError{Message: "not found"}
+	}
+	return dir, nil
+}
+
+func (f fakeFetcher) ValidPath(importPath string) bool {
+	return importPath != ""
+}
+
+// useFakeFetcher replaces the fetcher and store for a test and returns a
+// function that restores them.
+func useFakeFetcher(f fakeFetcher) func() {
+	oldFetcher, oldStore, oldCache := fetcher, store, hotPackages
+	fetcher, store, hotPackages = f, newMemoryStore(), newPackageCache(0, 0)
+	return func() { fetcher, store, hotPackages = oldFetcher, oldStore, oldCache }
+}
+
+func TestRunLint(t *testing.T) {
+	moved := &Directory{
+		ImportPath:  "github.com/new/repo/sub",
+		ProjectRoot: "github.com/new/repo",
+		LineFmt:     "%s#L%d",
+		Files: []*File{
+			{Name: "a.go", Data: []byte("package sub\n\nvar X_y int\n")},
+			{Name: "README.md", Data: []byte("# sub\n")},
+		},
+	}
+	defer useFakeFetcher(fakeFetcher{
+		"github.com/old/repo/sub": moved,
+		"github.com/new/repo": {
+			ImportPath:  "github.com/new/repo",
+			ProjectRoot: "github.com/new/repo",
+			Files:       []*File{{Name: "README.md", Data: []byte("# repo\n")}},
+		},
+	})()
+
+	i, err := aetest.NewInstance(nil)
+	if err != nil {
+		t.Skipf("App Engine development server not available: %v", err)
+	}
+	defer i.Close()
+	r, err := i.NewRequest("GET", "/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func(old func() time.Time) { now = old }(now)
+	t0 := time.Date(2016, 5, 1, 12, 0, 0, 0, time.UTC)
+	now = func() time.Time { return t0 }
+
+	pkg, err := runLint(r, "github.com/old/repo/sub", lintOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pkg.Path != "github.com/new/repo/sub" {
+		t.Errorf("Path = %q, want the canonical path", pkg.Path)
+	}
+	if !pkg.Updated.Equal(t0) {
+		t.Errorf("Updated = %v, want %v", pkg.Updated, t0)
+	}
+	if len(pkg.Files) != 1 || pkg.Files[0].Name != "sub/a.go" {
+		t.Fatalf("Files = %+v, want problems in sub/a.go", pkg.Files)
+	}
+
+	stored, err := store.Get(appengine.NewContext(r), "github.com/new/repo/sub")
+	if err != nil || stored == nil {
+		t.Errorf("package not stored under canonical path: %v, %v", stored, err)
+	}
+
+	if _, err := runLint(r, "github.com/missing/repo", lintOptions{}); err == nil {
+		t.Error("runLint of missing package returned no error")
+	} else if _, ok := err.(gosrc.NotFoundError); !ok {
+		t.Errorf("runLint of missing package returned %v, want gosrc.NotFoundError", err)
+	}
+
+	if _, err := runLint(r, "github.com/new/repo", lintOptions{}); err != (noPackageError{"github.com/new/repo"}) {
+		t.Errorf("runLint of directory without Go files returned %v, want noPackageError", err)
+	}
+}
+
+func TestGosrcFetcherRevision(t *testing.T) {
+	if _, err := (gosrcFetcher{}).Fetch(context.Background(), "github.com/user/repo", "v1.0.0"); err != errRevisionUnsupported {
+		t.Errorf("Fetch of a revision returned %v, want errRevisionUnsupported", err)
+	}
+}
+
+// redirectFetcher redirects the import paths in the map and serves the
+// others from a fakeFetcher.
+type redirectFetcher struct {
+	redirects map[string]string
+	fakeFetcher
+}
+
+func (f redirectFetcher) Fetch(c context.Context, importPath, rev string) (*Directory, error) {
+	if to, ok := f.redirects[importPath]; ok {
+		return nil, gosrc.NotFoundError{Message: "moved", Redirect: to}
+	}
+	return f.fakeFetcher.Fetch(c, importPath, rev)
+}
+
+func TestFetchPackageRedirects(t *testing.T) {
+	dir := &Directory{ImportPath: "github.com/d/repo", Files: []*File{{Name: "a.go", Data: []byte("package repo\n")}}}
+	defer useFakeFetcher(nil)()
+	fetcher = redirectFetcher{
+		redirects: map[string]string{
+			"github.com/a/repo":    "github.com/b/repo",
+			"github.com/b/repo":    "github.com/c/repo",
+			"github.com/c/repo":    "github.com/d/repo",
+			"github.com/loop/one":  "github.com/loop/two",
+			"github.com/loop/two":  "github.com/loop/one",
+			"github.com/far/0":     "github.com/far/1",
+			"github.com/far/1":     "github.com/far/2",
+			"github.com/far/2":     "github.com/far/3",
+			"github.com/far/3":     "github.com/far/4",
+			"github.com/self/repo": "github.com/self/repo",
+		},
+		fakeFetcher: fakeFetcher{"github.com/d/repo": dir, "github.com/far/4": dir},
+	}
+
+	got, err := fetchPackage(context.Background(), "github.com/a/repo", "")
+	if err != nil || got != dir {
+		t.Errorf("fetchPackage of redirected path = %v, %v, want github.com/d/repo", got, err)
+	}
+
+	for _, tt := range []struct {
+		path string
+		loop bool
+		want string
+	}{
+		{"github.com/loop/one", true, "redirect loop detected: github.com/loop/one -> github.com/loop/two -> github.com/loop/one"},
+		{"github.com/self/repo", true, "redirect loop detected: github.com/self/repo -> github.com/self/repo"},
+		{"github.com/far/0", false, "too many redirects: github.com/far/0 -> github.com/far/1 -> github.com/far/2 -> github.com/far/3 -> github.com/far/4"},
+	} {
+		_, err := fetchPackage(context.Background(), tt.path, "")
+		e, ok := err.(*redirectError)
+		if !ok {
+			t.Errorf("fetchPackage(%q) returned %v, want a redirectError", tt.path, err)
+			continue
+		}
+		if e.loop != tt.loop || e.Error() != tt.want {
+			t.Errorf("fetchPackage(%q) returned %q (loop %v), want %q (loop %v)", tt.path, e, e.loop, tt.want, tt.loop)
+		}
+		if !isNotFound(err) {
+			t.Errorf("isNotFound(%v) returned false", err)
+		}
+	}
+}
+
+func TestGosrcFetcherValidPath(t *testing.T) {
+	for _, tt := range []struct {
+		path string
+		want bool
+	}{
+		{"github.com/user/repo", true},
+		{"github.com/user/repo/sub", true},
+		{"example", false},
+	} {
+		if got := (gosrcFetcher{}).ValidPath(tt.path); got != tt.want {
+			t.Errorf("ValidPath(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestLintFilesEmptyData(t *testing.T) {
+	f := fakeFetcher{"github.com/user/repo": {
+		ImportPath:  "github.com/user/repo",
+		ProjectRoot: "github.com/user/repo",
+		Files: []*File{
+			{Name: "a.go", Data: []byte("package repo\n\nvar X_y int\n")},
+			{Name: "b.go"},
+		},
+	}}
+	dir, err := f.Fetch(context.Background(), "github.com/user/repo", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkg := &lintPackage{Path: dir.ImportPath}
+	lintFiles(context.Background(), pkg, dir.Files, nil)
+	if len(pkg.Unfetched) != 1 || pkg.Unfetched[0] != "b.go" {
+		t.Errorf("Unfetched = %v, want [b.go]", pkg.Unfetched)
+	}
+	if len(pkg.Files) != 1 || pkg.Files[0].Name != "a.go" {
+		t.Errorf("Files = %+v, want problems in a.go only", pkg.Files)
+	}
+}
+
+func TestHasGoFiles(t *testing.T) {
+	if hasGoFiles([]*File{{Name: "README.md"}, {Name: "LICENSE"}}) {
+		t.Error("hasGoFiles of a directory without Go files returned true")
+	}
+	if !hasGoFiles([]*File{{Name: "README.md"}, {Name: "a.go"}}) {
+		t.Error("hasGoFiles of a directory with Go files returned false")
+	}
+	if !isNotFound(noPackageError{"github.com/user/repo"}) {
+		t.Error("isNotFound(noPackageError) returned false")
+	}
+}
+
+func TestLineFmtOverrides(t *testing.T) {
+	defer func(old map[string]string) { lineFmtOverrides = old }(lineFmtOverrides)
+	lineFmtOverrides = parseLineFmtOverrides("git.example.com=%s?line=%d, Code.Example.org = %s#n%d,bad=%s,=%s#%d,noformat")
+	want := map[string]string{
+		"git.example.com":  "%s?line=%d",
+		"code.example.org": "%s#n%d",
+	}
+	if !reflect.DeepEqual(lineFmtOverrides, want) {
+		t.Errorf("parseLineFmtOverrides = %v, want %v", lineFmtOverrides, want)
+	}
+	tests := []struct {
+		importPath, fetched, want string
+	}{
+		{"git.example.com/team/repo", "", "%s?line=%d"},
+		{"git.example.com/team/repo", "%s#L%d", "%s?line=%d"},
+		{"github.com/user/repo", "%s#L%d", "%s#L%d"},
+		{"other.example.com/repo", "", ""},
+	}
+	for _, tt := range tests {
+		if got := lineFmt(tt.importPath, tt.fetched); got != tt.want {
+			t.Errorf("lineFmt(%q, %q) = %q, want %q", tt.importPath, tt.fetched, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package lintapp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/token"
+	"net/http"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// counts returns the number of problems in pkg and the number of files with
+// at least one problem.
+func (pkg *lintPackage) counts() (problems, files int) {
+	for _, f := range pkg.Files {
+		if len(f.Problems) > 0 {
+			problems += len(f.Problems)
+			files++
+		}
+	}
+	return problems, files
+}
+
+func plural(n int, one, many string) string {
+	if n == 1 {
+		return "1 " + one
+	}
+	return fmt.Sprintf("%d %s", n, many)
+}
+
+func writeTextResponse(w http.ResponseWriter, status int, s string) error {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Content-Length", strconv.Itoa(len(s)))
+	w.WriteHeader(status)
+	_, err := w.Write([]byte(s))
+	return err
+}
+
+func writeJSONResponse(w http.ResponseWriter, status int, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	w.WriteHeader(status)
+	_, err = w.Write(data)
+	return err
+}
+
+// writeSummaryResponse writes a single line describing the filtered problems
+// in pkg, suitable for posting to chat.
+func writeSummaryResponse(w http.ResponseWriter, pkg *lintPackage, minConfidence float64) error {
+	problems, files := pkg.counts()
+	if problems == 0 {
+		return writeTextResponse(w, 200, "clean\n")
+	}
+	return writeTextResponse(w, 200, fmt.Sprintf("%s: %s in %s (min confidence %.2f)\n",
+		pkg.Path, plural(problems, "problem", "problems"), plural(files, "file", "files"), minConfidence))
+}
+
+// problemURL returns the URL of the line of problem p in file f of pkg on
+// the source host. It returns the URL of the file if the problem has no line
+// or the package has no usable LineFmt, and "" if the file has no URL.
+func problemURL(pkg *lintPackage, f *lintFile, p *lintProblem) string {
+	if f.URL == "" || p.Line == 0 || pkg.LineFmt == "" {
+		return f.URL
+	}
+	u := fmt.Sprintf(pkg.LineFmt, f.URL, p.Line)
+	if strings.Contains(u, "%!") {
+		return f.URL
+	}
+	return u
+}
+
+// writeIssueResponse writes the filtered problems in pkg as Markdown
+// formatted for pasting into a new GitHub issue.
+func writeIssueResponse(w http.ResponseWriter, r *http.Request, pkg *lintPackage, minConfidence float64) error {
+	problems, _ := pkg.counts()
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "Title: Fix %s reported by golint in %s\n\n", plural(problems, "problem", "problems"), pkg.Path)
+	fmt.Fprintf(&buf, "[golint](https://github.com/golang/lint) reported the following problems in `%s` (min confidence %.2f):\n", pkg.Path, minConfidence)
+	for _, f := range pkg.Files {
+		if len(f.Problems) == 0 {
+			continue
+		}
+		fmt.Fprintf(&buf, "\n### %s\n\n", f.Name)
+		for _, p := range f.Problems {
+			switch u := problemURL(pkg, f, p); {
+			case p.Line == 0:
+				fmt.Fprintf(&buf, "- [ ] %s\n", p.Text)
+			case u != "":
+				fmt.Fprintf(&buf, "- [ ] [%s:%d](%s): %s\n", f.Name, p.Line, u, p.Text)
+			default:
+				fmt.Fprintf(&buf, "- [ ] %s:%d: %s\n", f.Name, p.Line, p.Text)
+			}
+		}
+	}
+	fmt.Fprintf(&buf, "\n---\nReported by [%s](http://%s/%s).\n", r.Host, r.Host, pkg.Path)
+	w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+	w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+	w.WriteHeader(200)
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// setRepoRelativeNames prefixes the names of the files in pkg, which are
+// file names with no directory when linted, with dir, the directory of the
+// package relative to the repository root.
+func setRepoRelativeNames(pkg *lintPackage, dir string) {
+	if dir == "" {
+		return
+	}
+	for _, f := range pkg.Files {
+		f.Name = path.Join(dir, f.Name)
+	}
+	for i, name := range pkg.Generated {
+		pkg.Generated[i] = path.Join(dir, name)
+	}
+	for i, name := range pkg.Unfetched {
+		pkg.Unfetched[i] = path.Join(dir, name)
+	}
+}
+
+// setFullNames replaces the repository relative names of the files in pkg with
+// paths qualified by the import path of the package, as requested with the
+// fullPath parameter.
+func setFullNames(pkg *lintPackage) {
+	for _, f := range pkg.Files {
+		f.Name = path.Join(pkg.Path, path.Base(f.Name))
+	}
+	for i, name := range pkg.Generated {
+		pkg.Generated[i] = path.Join(pkg.Path, path.Base(name))
+	}
+	for i, name := range pkg.Unfetched {
+		pkg.Unfetched[i] = path.Join(pkg.Path, path.Base(name))
+	}
+}
+
+// writeGolintResponse writes the filtered problems in pkg in the format of the
+// golint command run at the repository root: one file:line:col: message
+// line per problem, ordered by file name and position.
+func writeGolintResponse(w http.ResponseWriter, pkg *lintPackage) error {
+	files := make([]*lintFile, len(pkg.Files))
+	copy(files, pkg.Files)
+	sort.Sort(byFileName(files))
+	var buf bytes.Buffer
+	for _, f := range files {
+		for _, p := range f.Problems {
+			pos := token.Position{Filename: f.Name, Line: p.Line, Column: p.Column}
+			fmt.Fprintf(&buf, "%v: %s\n", pos, p.Text)
+		}
+	}
+	return writeTextResponse(w, 200, buf.String())
+}
+
+type byFileName []*lintFile
+
+func (s byFileName) Len() int           { return len(s) }
+func (s byFileName) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s byFileName) Less(i, j int) bool { return s[i].Name < s[j].Name }
@@ -0,0 +1,91 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package lintapp
+
+import (
+	"bytes"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang/lint"
+	"golang.org/x/net/context"
+)
+
+func TestWriteGolintResponse(t *testing.T) {
+	files := []*File{
+		{Name: "b.go", Data: []byte("package b\n\nfunc F_b() {}\n")},
+		{Name: "a.go", Data: []byte("package b\n\nvar X_a int\n\nfunc G() {}\n")},
+	}
+
+	// Format the problems the way the golint command does.
+	var want bytes.Buffer
+	for _, name := range []string{"a.go", "b.go"} {
+		for _, f := range files {
+			if f.Name != name {
+				continue
+			}
+			problems, err := new(lint.Linter).Lint(f.Name, f.Data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, p := range problems {
+				fmt.Fprintf(&want, "%v: %s\n", p.Position, p.Text)
+			}
+		}
+	}
+
+	pkg := &lintPackage{Path: "github.com/a/b"}
+	lintFiles(context.Background(), pkg, files, nil)
+	w := httptest.NewRecorder()
+	if err := writeGolintResponse(w, pkg); err != nil {
+		t.Fatal(err)
+	}
+	if got := w.Body.String(); got != want.String() {
+		t.Errorf("writeGolintResponse wrote\n%s\nwant\n%s", got, want.String())
+	}
+}
+
+func TestFileNames(t *testing.T) {
+	pkg := &lintPackage{
+		Path:      "github.com/a/b/c",
+		Files:     []*lintFile{{Name: "x.go"}},
+		Generated: []string{"y.pb.go"},
+	}
+	setRepoRelativeNames(pkg, "c")
+	if pkg.Files[0].Name != "c/x.go" || pkg.Generated[0] != "c/y.pb.go" {
+		t.Errorf("repository relative names are %q, %q; want c/x.go, c/y.pb.go", pkg.Files[0].Name, pkg.Generated[0])
+	}
+	setFullNames(pkg)
+	if pkg.Files[0].Name != "github.com/a/b/c/x.go" || pkg.Generated[0] != "github.com/a/b/c/y.pb.go" {
+		t.Errorf("full names are %q, %q; want github.com/a/b/c/x.go, github.com/a/b/c/y.pb.go", pkg.Files[0].Name, pkg.Generated[0])
+	}
+}
+
+var problemURLTests = []struct {
+	lineFmt, fileURL string
+	line             int
+	want             string
+}{
+	{"%s#L%d", "https://github.com/user/repo/blob/master/a.go", 12, "https://github.com/user/repo/blob/master/a.go#L12"},
+	{"%s?line=%d", "https://git.example.com/a.go", 3, "https://git.example.com/a.go?line=3"},
+	{"%s#L%d", "https://github.com/user/repo/blob/master/a.go", 0, "https://github.com/user/repo/blob/master/a.go"},
+	{"", "https://example.com/a.go", 12, "https://example.com/a.go"},
+	{"%s", "https://example.com/a.go", 12, "https://example.com/a.go"},
+	{"%s#L%d", "", 12, ""},
+	{"", "", 12, ""},
+}
+
+func TestProblemURL(t *testing.T) {
+	for _, tt := range problemURLTests {
+		pkg := &lintPackage{LineFmt: tt.lineFmt}
+		f := &lintFile{Name: "a.go", URL: tt.fileURL}
+		if got := problemURL(pkg, f, &lintProblem{Line: tt.line}); got != tt.want {
+			t.Errorf("problemURL(LineFmt %q, URL %q, line %d) = %q, want %q", tt.lineFmt, tt.fileURL, tt.line, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+// +build ignore
+
+// This program writes the templates in assets/templates to a Go file built
+// with the embedtemplates tag, for deployments without the assets directory.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"go/format"
+	"io/ioutil"
+	"log"
+	"path/filepath"
+	"text/template"
+)
+
+var tmpl = template.Must(template.New("").Parse(`// Created by go generate; DO NOT EDIT
+// Copyright 2016 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+// +build embedtemplates
+
+package lintapp
+
+var embeddedTemplates = map[string]string{
+{{range $k, $v := .}}{{printf "%q" $k}}: {{printf "%q" $v}},
+{{end}} }
+`))
+
+var (
+	dir    = flag.String("dir", "assets/templates", "directory of the templates")
+	output = flag.String("output", "templates_embedded.go", "file name to write")
+)
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("gentemplates: ")
+	flag.Parse()
+	if flag.NArg() != 0 {
+		log.Fatal("usage: gentemplates [--dir directory] [--output filename]")
+	}
+
+	paths, err := filepath.Glob(filepath.Join(*dir, "*.html"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(paths) == 0 {
+		log.Fatalf("no templates in %s", *dir)
+	}
+	templates := make(map[string]string)
+	for _, p := range paths {
+		data, err := ioutil.ReadFile(p)
+		if err != nil {
+			log.Fatal(err)
+		}
+		templates[filepath.Base(p)] = string(data)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, templates); err != nil {
+		log.Fatal("template error: ", err)
+	}
+	source, err := format.Source(buf.Bytes())
+	if err != nil {
+		log.Fatal("source format error: ", err)
+	}
+	if err := ioutil.WriteFile(*output, source, 0666); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,60 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package lintapp
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// The gha format writes the problems as GitHub Actions workflow commands,
+// which Actions shows as annotations on the lines of a pull request. See
+// https://docs.github.com/actions/reference/workflow-commands-for-github-actions.
+
+var (
+	// ghaDataEscaper escapes the message of a workflow command.
+	ghaDataEscaper = strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A")
+
+	// ghaPropertyEscaper escapes the values of the file, line, col and
+	// title parameters of a workflow command.
+	ghaPropertyEscaper = strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C")
+)
+
+// ghaCommand returns the command for a problem: error for a file that could
+// not be linted and warning for a problem found by golint.
+func ghaCommand(p *lintProblem) string {
+	if p.Line == 0 {
+		return "error"
+	}
+	return "warning"
+}
+
+func writeGHAResponse(w http.ResponseWriter, pkg *lintPackage) error {
+	files := make([]*lintFile, len(pkg.Files))
+	copy(files, pkg.Files)
+	sort.Sort(byFileName(files))
+	var buf bytes.Buffer
+	for _, f := range files {
+		for _, p := range f.Problems {
+			fmt.Fprintf(&buf, "::%s file=%s", ghaCommand(p), ghaPropertyEscaper.Replace(f.Name))
+			if p.Line > 0 {
+				fmt.Fprintf(&buf, ",line=%d", p.Line)
+			}
+			if p.Column > 0 {
+				fmt.Fprintf(&buf, ",col=%d", p.Column)
+			}
+			if p.RuleID != "" {
+				fmt.Fprintf(&buf, ",title=%s", ghaPropertyEscaper.Replace("golint "+p.RuleID))
+			}
+			fmt.Fprintf(&buf, "::%s\n", ghaDataEscaper.Replace(p.Text))
+		}
+	}
+	return writeTextResponse(w, 200, buf.String())
+}
@@ -0,0 +1,44 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package lintapp
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWriteGHAResponse(t *testing.T) {
+	pkg := &lintPackage{Files: []*lintFile{
+		{
+			Name: "sub/b,c.go",
+			Problems: []*lintProblem{
+				{Line: 5, Column: 2, Text: "100% sure\nnot", Confidence: 0.3},
+			},
+		},
+		{
+			Name: "a.go",
+			Problems: []*lintProblem{
+				{Line: 3, Column: 6, Text: "exported F should have comment or be unexported", Confidence: 1, RuleID: "exported-comment"},
+				{Text: "a.go:1:1: expected 'package', found 'EOF'"},
+			},
+		},
+	}}
+	w := httptest.NewRecorder()
+	if err := writeGHAResponse(w, pkg); err != nil {
+		t.Fatal(err)
+	}
+	const want = `::warning file=a.go,line=3,col=6,title=golint exported-comment::exported F should have comment or be unexported
+::error file=a.go::a.go:1:1: expected 'package', found 'EOF'
+::warning file=sub/b%2Cc.go,line=5,col=2::100%25 sure%0Anot
+`
+	if got := w.Body.String(); got != want {
+		t.Errorf("body =\n%s\nwant\n%s", got, want)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "text/plain; charset=utf-8" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+}
@@ -0,0 +1,148 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package lintapp
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"path"
+	"sort"
+	"time"
+
+	"golang.org/x/net/context"
+	"google.golang.org/appengine/datastore"
+	"google.golang.org/appengine/log"
+	"google.golang.org/appengine/memcache"
+)
+
+// Each lint result is also stored as a Snapshot entity with the Package
+// entity as parent. The snapshot ID is the Unix time of the result's Updated
+// field. Only the maxSnapshots most recent snapshots of a package are kept.
+var maxSnapshots = 10
+
+func snapshotKey(c context.Context, parent *datastore.Key, updated time.Time) *datastore.Key {
+	return datastore.NewKey(c, "Snapshot", "", updated.Unix(), parent)
+}
+
+// pruneSnapshots deletes all but the maxSnapshots most recent snapshots of
+// the package with the given key.
+func pruneSnapshots(c context.Context, parent *datastore.Key) error {
+	// Keys with integer IDs sort by ID, oldest snapshot first.
+	keys, err := datastore.NewQuery("Snapshot").Ancestor(parent).KeysOnly().GetAll(c, nil)
+	if err != nil {
+		return err
+	}
+	if len(keys) <= maxSnapshots {
+		return nil
+	}
+	return datastore.DeleteMulti(c, keys[:len(keys)-maxSnapshots])
+}
+
+// cleanedFile is a file without problems that had problems in an earlier
+// snapshot of its package.
+type cleanedFile struct {
+	Name string
+
+	// Since is the update time of the first result without problems in
+	// the file.
+	Since time.Time
+}
+
+// cleanedFiles returns the linted files of pkg without problems that had
+// problems in one of the earlier snapshots, sorted by name. Problems shown
+// collapsed in pkg are not counted.
+func cleanedFiles(pkg *lintPackage, snapshots []*lintPackage) []cleanedFile {
+	// since maps the names of files that had problems to the time they were
+	// last cleaned, or to the zero time if they have problems.
+	since := make(map[string]time.Time)
+	update := func(updated time.Time, problems map[string]bool) {
+		for name := range problems {
+			since[name] = time.Time{}
+		}
+		for name, t := range since {
+			if t.IsZero() && !problems[name] {
+				since[name] = updated
+			}
+		}
+	}
+	for _, s := range snapshots {
+		if !s.Updated.Before(pkg.Updated) {
+			continue
+		}
+		problems := make(map[string]bool)
+		for _, f := range s.Files {
+			if len(f.Problems) > 0 {
+				problems[f.Name] = true
+			}
+		}
+		update(s.Updated, problems)
+	}
+	problems := make(map[string]bool)
+	for _, f := range pkg.Files {
+		if f.ProblemCount() > 0 {
+			problems[f.Name] = true
+		}
+	}
+	update(pkg.Updated, problems)
+
+	var files []cleanedFile
+	for name, t := range since {
+		if !t.IsZero() && pkg.Hashes[path.Base(name)] != "" {
+			files = append(files, cleanedFile{Name: name, Since: t})
+		}
+	}
+	sort.Sort(cleanedFilesByName(files))
+	return files
+}
+
+type cleanedFilesByName []cleanedFile
+
+func (s cleanedFilesByName) Len() int           { return len(s) }
+func (s cleanedFilesByName) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s cleanedFilesByName) Less(i, j int) bool { return s[i].Name < s[j].Name }
+
+// cleanedFilesExpiration is how long the cleaned files of a result are kept
+// in memcache. Entries are keyed by the result's update time, so they do not
+// go stale when the package is linted again.
+const cleanedFilesExpiration = 24 * time.Hour
+
+// cleanedFilesKey returns the memcache key of the cleaned files of pkg,
+// stored under key, for the confidence parameters of r.
+func cleanedFilesKey(r *http.Request, key string, pkg *lintPackage) string {
+	s := fmt.Sprintf("%s\x00%d\x00%g\x00%g", key, pkg.Updated.UnixNano(), minConfidence(r, pkg.Path), maxConfidence(r))
+	h := sha1.Sum([]byte(s))
+	return "cleaned:" + hex.EncodeToString(h[:])
+}
+
+// getCleanedFiles returns the cleaned files of pkg, stored under key, with the
+// snapshots filtered by the confidence parameters of r. The files are cached
+// in memcache, since finding them decodes all snapshots of the package.
+// Errors are logged because the files are only informational.
+func getCleanedFiles(c context.Context, r *http.Request, key string, pkg *lintPackage) []cleanedFile {
+	mkey := cleanedFilesKey(r, key, pkg)
+	var cached struct{ Files []cleanedFile }
+	if _, err := memcache.Gob.Get(c, mkey, &cached); err == nil {
+		return cached.Files
+	} else if err != memcache.ErrCacheMiss {
+		log.Warningf(c, "Getting cached cleaned files of %s: %v", key, err)
+	}
+	snapshots, err := store.GetSnapshots(c, key)
+	if err != nil {
+		log.Warningf(c, "Getting snapshots of %s: %v", key, err)
+		return nil
+	}
+	for _, s := range snapshots {
+		filterByConfidence(r, s)
+	}
+	cached.Files = cleanedFiles(pkg, snapshots)
+	if err := memcache.Gob.Set(c, &memcache.Item{Key: mkey, Object: &cached, Expiration: cleanedFilesExpiration}); err != nil {
+		log.Warningf(c, "Caching cleaned files of %s: %v", key, err)
+	}
+	return cached.Files
+}
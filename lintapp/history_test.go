@@ -0,0 +1,68 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package lintapp
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCleanedFiles(t *testing.T) {
+	at := func(day int) time.Time { return time.Date(2016, 5, day, 0, 0, 0, 0, time.UTC) }
+	withProblems := func(day int, names ...string) *lintPackage {
+		pkg := &lintPackage{Updated: at(day)}
+		for _, name := range names {
+			pkg.Files = append(pkg.Files, &lintFile{Name: name, Problems: []*lintProblem{{Text: "p"}}})
+		}
+		return pkg
+	}
+	snapshots := []*lintPackage{
+		withProblems(1, "sub/a.go", "sub/b.go", "sub/c.go", "sub/gone.go"),
+		withProblems(2, "sub/b.go", "sub/c.go"),
+		withProblems(3, "sub/a.go", "sub/c.go"),
+		withProblems(4, "sub/c.go"),
+	}
+	pkg := withProblems(5, "sub/d.go")
+	pkg.Files = append(pkg.Files, &lintFile{Name: "sub/e.go", Problems: []*lintProblem{{Text: "low", BelowThreshold: true}}})
+	pkg.Hashes = map[string]string{"a.go": "1", "b.go": "2", "c.go": "3", "d.go": "4", "e.go": "5"}
+	snapshots = append(snapshots, pkg)
+
+	want := []cleanedFile{
+		{Name: "sub/a.go", Since: at(4)},
+		{Name: "sub/b.go", Since: at(3)},
+		{Name: "sub/c.go", Since: at(5)},
+	}
+	if got := cleanedFiles(pkg, snapshots); !reflect.DeepEqual(got, want) {
+		t.Errorf("cleanedFiles = %+v, want %+v", got, want)
+	}
+}
+
+func TestCleanedFilesKey(t *testing.T) {
+	t0 := time.Date(2016, 5, 1, 0, 0, 0, 0, time.UTC)
+	pkg := &lintPackage{Path: "github.com/user/repo", Updated: t0}
+	later := &lintPackage{Path: "github.com/user/repo", Updated: t0.Add(time.Second)}
+	request := func(query string) *http.Request {
+		r, _ := http.NewRequest("GET", "/github.com/user/repo"+query, nil)
+		return r
+	}
+	key := cleanedFilesKey(request(""), "github.com/user/repo", pkg)
+	if k := cleanedFilesKey(request(""), "github.com/user/repo", pkg); k != key {
+		t.Errorf("key changed between calls: %q, %q", key, k)
+	}
+	for _, k := range []string{
+		cleanedFilesKey(request(""), "github.com/user/repo", later),
+		cleanedFilesKey(request(""), "github.com/user/repo?goos=windows", pkg),
+		cleanedFilesKey(request("?minConfidence=0.2"), "github.com/user/repo", pkg),
+		cleanedFilesKey(request("?maxConfidence=0.9"), "github.com/user/repo", pkg),
+	} {
+		if k == key {
+			t.Errorf("key %q is the same for a different result or filter", k)
+		}
+	}
+}
@@ -0,0 +1,270 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package lintapp
+
+import (
+	"fmt"
+	"html/template"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A locale holds the user interface strings for one language. Templates get
+// the strings with the msg, msgn and msgHTML functions.
+type locale struct {
+	// Tag is the language tag, such as "en" or "pt-BR".
+	Tag string
+
+	// Messages maps message keys to fmt format strings. The keys of
+	// messages with plural forms end with the plural form returned by
+	// Plural, such as "problems.one" and "problems.other".
+	Messages map[string]string
+
+	// Plural returns the plural form for n.
+	Plural func(n int) string
+}
+
+// locales maps lower case language tags to registered locales.
+var locales = map[string]*locale{}
+
+// defaultLocale is used when the request does not select a registered
+// locale. Messages missing from other locales are taken from it.
+var defaultLocale = english
+
+// registerLocale adds l to the locales that requests can select.
+func registerLocale(l *locale) {
+	locales[strings.ToLower(l.Tag)] = l
+}
+
+func init() {
+	registerLocale(english)
+}
+
+func (l *locale) format(key string) string {
+	if s, ok := l.Messages[key]; ok {
+		return s
+	}
+	if s, ok := defaultLocale.Messages[key]; ok {
+		return s
+	}
+	return key
+}
+
+// msg returns the message for key formatted with args.
+func (l *locale) msg(key string, args ...interface{}) string {
+	return fmt.Sprintf(l.format(key), args...)
+}
+
+// msgn returns the plural form of the message for key selected by n,
+// formatted with n followed by args. A message that does not use n, such as
+// "one hour ago", must refer to the other arguments by explicit index, as in
+// "one problem in %[2]s", or have no verbs.
+func (l *locale) msgn(key string, n int, args ...interface{}) string {
+	plural := defaultLocale.Plural
+	if l.Plural != nil {
+		plural = l.Plural
+	}
+	format := l.format(key + "." + plural(n))
+	if !strings.Contains(format, "%") {
+		return format
+	}
+	return fmt.Sprintf(format, append([]interface{}{n}, args...)...)
+}
+
+// msgHTML returns the message for key, which contains trusted HTML,
+// formatted with args escaped as HTML text.
+func (l *locale) msgHTML(key string, args ...interface{}) template.HTML {
+	for i, arg := range args {
+		args[i] = template.HTMLEscapeString(fmt.Sprint(arg))
+	}
+	return template.HTML(fmt.Sprintf(l.format(key), args...))
+}
+
+// timeago returns how long ago t was, or never for the zero time.
+func (l *locale) timeago(t time.Time) string {
+	d := now().Sub(t)
+	switch {
+	case t.IsZero():
+		return l.msg("timeago.never")
+	case d < time.Second:
+		return l.msg("timeago.now")
+	case d < time.Minute:
+		return l.msgn("timeago.seconds", int(d/time.Second))
+	case d < time.Hour:
+		return l.msgn("timeago.minutes", int(d/time.Minute))
+	case d < 48*time.Hour:
+		return l.msgn("timeago.hours", int(d/time.Hour))
+	default:
+		return l.msgn("timeago.days", int(d/(24*time.Hour)))
+	}
+}
+
+// funcs returns the template functions for the locale.
+func (l *locale) funcs() template.FuncMap {
+	return template.FuncMap{
+		"lang":    func() string { return l.Tag },
+		"msg":     l.msg,
+		"msgn":    l.msgn,
+		"msgHTML": l.msgHTML,
+		"timeago": l.timeago,
+	}
+}
+
+// requestLocale returns the locale selected by the lang parameter or, if
+// the parameter does not name a registered locale, by the Accept-Language
+// header. A language range matches a locale with the same tag or, failing
+// that, with the same primary language.
+func requestLocale(r *http.Request) *locale {
+	if l := matchLocale(r.FormValue("lang")); l != nil {
+		return l
+	}
+	best, bestQ := defaultLocale, 0.0
+	for _, s := range strings.Split(r.Header.Get("Accept-Language"), ",") {
+		params := strings.Split(s, ";")
+		q := 1.0
+		for _, p := range params[1:] {
+			p = strings.TrimSpace(p)
+			if strings.HasPrefix(p, "q=") {
+				var err error
+				if q, err = strconv.ParseFloat(p[2:], 64); err != nil {
+					q = 0
+				}
+			}
+		}
+		if l := matchLocale(params[0]); l != nil && q > bestQ {
+			best, bestQ = l, q
+		}
+	}
+	return best
+}
+
+func matchLocale(tag string) *locale {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if tag == "" {
+		return nil
+	}
+	if l := locales[tag]; l != nil {
+		return l
+	}
+	if i := strings.IndexAny(tag, "-_"); i > 0 {
+		return locales[tag[:i]]
+	}
+	return nil
+}
+
+var english = &locale{
+	Tag: "en",
+	Plural: func(n int) string {
+		if n == 1 {
+			return "one"
+		}
+		return "other"
+	},
+	Messages: map[string]string{
+		"timeago.never":         "never",
+		"timeago.now":           "just now",
+		"timeago.seconds.one":   "one second ago",
+		"timeago.seconds.other": "%d seconds ago",
+		"timeago.minutes.one":   "one minute ago",
+		"timeago.minutes.other": "%d minutes ago",
+		"timeago.hours.one":     "one hour ago",
+		"timeago.hours.other":   "%d hours ago",
+		"timeago.days.one":      "one day ago",
+		"timeago.days.other":    "%d days ago",
+
+		"footer.home":     "Home",
+		"footer.feedback": "Feedback",
+		"footer.issues":   "Website Issues",
+		"footer.version":  "Version %s",
+
+		"home.heading":     "Go Lint",
+		"home.intro":       `Go Lint lints <a href="http://golang.org/">Go</a> source files on GitHub, Bitbucket and Google Project Hosting using the <a href="https://github.com/golang/lint">lint package</a>.`,
+		"home.placeholder": "Package import path",
+		"home.submit":      "Lint",
+
+		"package.title":                 "Lint %s",
+		"package.heading":               "Lint for",
+		"package.command":               "command",
+		"package.library":               "library",
+		"package.scoreFormula":          "100 × lines of code / (lines of code + 10 × problems), for problems with confidence at least 0.8",
+		"package.score":                 "Lint score",
+		"package.lines.one":             "for %d line of code",
+		"package.lines.other":           "for %d lines of code",
+		"package.highProblemCount":      "This package has a high number of lint issues.",
+		"package.lowConfidence":         "You've revealed %d very low confidence suggestions; these are rarely actionable.",
+		"package.defaultConfidence":     "Show only likely problems",
+		"package.compact":               "Source lines are not shown because the result is large. See the annotated source below.",
+		"package.linterChanged":         "Results updated for a newer golint; counts may have changed.",
+		"package.deprecated":            "This package is deprecated.",
+		"package.multiple":              "This directory contains multiple packages; the problems of all of them are shown:",
+		"package.note":                  "Note:",
+		"package.platform":              "Files were selected for %s/%s.",
+		"package.defaultGOOS":           "the default GOOS",
+		"package.defaultGOARCH":         "the default GOARCH",
+		"package.rule":                  "Showing only problems for rule",
+		"package.allProblems":           "Show all problems",
+		"package.api":                   "Showing only problems concerning the exported API.",
+		"package.apiRules":              "Rules",
+		"package.file":                  "Showing only problems in",
+		"package.allFiles":              "Show all files",
+		"package.skipped.one":           "%d generated file skipped.",
+		"package.skipped.other":         "%d generated files skipped.",
+		"package.lintGenerated":         "Lint generated files",
+		"package.unfetched":             "Could not retrieve file contents:",
+		"package.unanalyzed":            "Could not analyze these files; their errors are not counted as problems:",
+		"package.unlinted.one":          "%d file not linted because the package has many files.",
+		"package.unlinted.other":        "%d files not linted because the package has many files.",
+		"package.lintAll":               "Lint all files",
+		"package.snapshotExpired":       "This permalink's snapshot expired. Showing the current report.",
+		"package.generatedAt":           "This report was generated",
+		"package.currentReport":         "Current report",
+		"package.refreshPending":        "Refresh in progress.",
+		"package.refresh":               "Refresh",
+		"package.permalink":             "Permalink",
+		"package.fileProblems.one":      "%d problem in %s",
+		"package.fileProblems.other":    "%d problems in %s",
+		"package.moreSuggestions.one":   "Show 1 more suggestion",
+		"package.moreSuggestions.other": "Show %d more suggestions",
+		"package.truncated":             "(truncated)",
+		"package.suggested":             "suggested: %s",
+		"package.location":              "Location",
+		"package.confidence":            "Confidence",
+		"package.problem":               "Problem",
+		"package.cleaned":               "Cleaned up:",
+		"package.annotatedSource":       "Annotated source:",
+		"package.similar":               "Other packages in this repository:",
+		"package.problems.one":          "%d problem",
+		"package.problems.other":        "%d problems",
+
+		"source.title":       "Lint %s in %s",
+		"source.in":          "in",
+		"source.allProblems": "All problems",
+
+		"compare.title":    "Comparison of %s and %s",
+		"compare.problems": "Problems",
+		"compare.files":    "Files with problems",
+		"compare.score":    "Score",
+		"compare.byRule":   "Problems by rule",
+
+		"rules.title":       "Lint rules",
+		"rules.intro":       "Problems are classified by matching golint's message with these rules. Show the problems of a package for one rule with /<import path>/rule/<id>, or for the rules marked api=1 with /<import path>?api=1. The naming rules marked api=1 select only problems with exported names.",
+		"rules.id":          "Rule",
+		"rules.description": "Description",
+		"rules.example":     "Example message",
+
+		"refresh.title":  "Refreshing %s",
+		"refresh.queued": "The package will be linted in the background.",
+		"refresh.view":   "View the report",
+
+		"error.notFound":     "Check that the import path is correct and that the package is hosted on GitHub, Bitbucket or another supported host.",
+		"error.unauthorized": "This site is private. Sign in or provide the access secret to continue.",
+		"error.forbidden":    "You do not have permission to view this page.",
+		"error.server":       `Something went wrong on our side. Try again later, or <a href="mailto:%s">let us know</a> if the problem persists.`,
+	},
+}
@@ -0,0 +1,81 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package lintapp
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestRequestLocale(t *testing.T) {
+	defer func(m map[string]*locale) { locales = m }(locales)
+	locales = map[string]*locale{}
+	registerLocale(english)
+	german := &locale{Tag: "de", Messages: map[string]string{"timeago.now": "gerade eben"}}
+	registerLocale(german)
+	brazilian := &locale{Tag: "pt-BR"}
+	registerLocale(brazilian)
+
+	tests := []struct {
+		query          string
+		acceptLanguage string
+		want           *locale
+	}{
+		{"", "", english},
+		{"", "de", german},
+		{"", "de-AT", german},
+		{"", "pt-br", brazilian},
+		{"", "fr, de;q=0.5", german},
+		{"", "de;q=0.5, en;q=0.9", english},
+		{"", "fr", english},
+		{"lang=de", "en", german},
+		{"lang=fr", "de", german},
+	}
+	for _, tt := range tests {
+		r, _ := http.NewRequest("GET", "/?"+tt.query, nil)
+		r.Header.Set("Accept-Language", tt.acceptLanguage)
+		if got := requestLocale(r); got != tt.want {
+			t.Errorf("requestLocale(%q, Accept-Language: %q) = %s, want %s", tt.query, tt.acceptLanguage, got.Tag, tt.want.Tag)
+		}
+	}
+
+	if got := german.timeago(time.Now()); got != "gerade eben" {
+		t.Errorf("german timeago = %q, want gerade eben", got)
+	}
+	if got := german.msgn("package.fileProblems", 2, "a.go"); got != "2 problems in a.go" {
+		t.Errorf("missing german message = %q, want English fallback", got)
+	}
+}
+
+func TestTimeago(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{0, "just now"},
+		{time.Second, "one second ago"},
+		{5 * time.Second, "5 seconds ago"},
+		{time.Minute - time.Nanosecond, "59 seconds ago"},
+		{time.Minute, "one minute ago"},
+		{time.Hour, "one hour ago"},
+		{47 * time.Hour, "47 hours ago"},
+		{48 * time.Hour, "2 days ago"},
+		{72 * time.Hour, "3 days ago"},
+	}
+	defer func(old func() time.Time) { now = old }(now)
+	t0 := time.Date(2016, 5, 1, 12, 0, 0, 0, time.UTC)
+	now = func() time.Time { return t0 }
+	for _, tt := range tests {
+		if got := english.timeago(t0.Add(-tt.d)); got != tt.want {
+			t.Errorf("timeago(%v) = %q, want %q", tt.d, got, tt.want)
+		}
+	}
+	if got := english.timeago(time.Time{}); got != "never" {
+		t.Errorf("timeago(zero time) = %q, want never", got)
+	}
+}
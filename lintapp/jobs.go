@@ -0,0 +1,249 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package lintapp
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/context"
+	"google.golang.org/appengine"
+	"google.golang.org/appengine/datastore"
+	"google.golang.org/appengine/log"
+	"google.golang.org/appengine/taskqueue"
+)
+
+// A job lints a list of packages in the background with refresh tasks. The
+// job is stored as a Job entity, and each finished task stores a JobResult
+// entity with the job as parent and the import path as name.
+
+var errJobTooLarge = &appError{Status: 413, Message: "Too many import paths."}
+
+const (
+	// maxJobPaths is the maximum number of import paths in a job.
+	maxJobPaths = 5000
+
+	// maxTasksPerAdd is the maximum number of tasks added to the task
+	// queue in one call.
+	maxTasksPerAdd = 100
+)
+
+type job struct {
+	Created time.Time
+	Paths   int
+	Options string `datastore:",noindex"`
+}
+
+type jobResult struct {
+	Path     string
+	Outcome  string
+	Problems int
+	Score    float64
+	Updated  time.Time
+}
+
+// jobStatus is the response of the job status endpoint.
+type jobStatus struct {
+	ID        int64
+	Created   time.Time
+	Paths     int
+	Completed int
+	NotFound  int
+	Problems  int
+	Done      bool
+	Results   []*jobResult
+}
+
+// parseJobPaths reads a newline separated list of import paths. Blank lines
+// and lines starting with # are ignored. Paths that are not valid are
+// returned separately.
+func parseJobPaths(r io.Reader) (paths, invalid []string, err error) {
+	seen := make(map[string]bool)
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		p := strings.TrimSpace(s.Text())
+		if p == "" || strings.HasPrefix(p, "#") || seen[p] {
+			continue
+		}
+		seen[p] = true
+		if checkPathLimits(p) != nil || !fetcher.ValidPath(p) {
+			invalid = append(invalid, p)
+			continue
+		}
+		paths = append(paths, p)
+	}
+	return paths, invalid, s.Err()
+}
+
+func jobKey(c context.Context, id int64) *datastore.Key {
+	return datastore.NewKey(c, "Job", "", id, nil)
+}
+
+// serveAdminJobs creates a job from the import paths listed in a POST body,
+// linted with the options in the query, and responds with the job ID. Bodies
+// longer than maxJobPaths paths of the maximum length are rejected with 413.
+// A GET with the id parameter responds with the status of the job.
+func serveAdminJobs(w http.ResponseWriter, r *http.Request) error {
+	if r.Method != "POST" {
+		return serveJobStatus(w, r)
+	}
+	limit := int64(maxJobPaths) * int64(maxPathLength+1)
+	if r.ContentLength > limit {
+		return errJobTooLarge
+	}
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, limit+1))
+	if err != nil {
+		return err
+	}
+	if int64(len(body)) > limit {
+		return errJobTooLarge
+	}
+	paths, invalid, err := parseJobPaths(bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	if len(paths) == 0 {
+		return &appError{Status: 400, Message: "No valid import paths."}
+	}
+	if len(paths) > maxJobPaths {
+		return &appError{Status: 400, Message: "Too many import paths."}
+	}
+	opts, err := parseLintOptions(r)
+	if err != nil {
+		return err
+	}
+
+	c := appengine.NewContext(r)
+	j := &job{
+		Created: now(),
+		Paths:   len(paths),
+		Options: opts.Values().Encode(),
+	}
+	k, err := datastore.Put(c, datastore.NewIncompleteKey(c, "Job", nil), j)
+	if err != nil {
+		return err
+	}
+	if queued, err := queueJobTasks(c, k.IntID(), paths, opts); err != nil {
+		log.Errorf(c, "Queueing job %d: %v", k.IntID(), err)
+		// Keep the job only for the paths queued, so that it completes.
+		if queued == 0 {
+			err = datastore.Delete(c, k)
+		} else {
+			j.Paths = queued
+			_, err = datastore.Put(c, k, j)
+		}
+		if err != nil {
+			log.Errorf(c, "Recording queued paths of job %d: %v", k.IntID(), err)
+		}
+		e := &appError{Status: 500, Message: fmt.Sprintf("Queued %d of %d import paths.", queued, len(paths))}
+		if queued > 0 {
+			e.Detail = fmt.Sprintf("Job %d lints the queued paths only.", k.IntID())
+		}
+		return e
+	}
+
+	id := strconv.FormatInt(k.IntID(), 10)
+	w.Header().Set("Location", "/-/admin/jobs?id="+id)
+	return writeJSONResponse(w, http.StatusAccepted, struct {
+		ID      int64
+		Paths   int
+		Invalid []string
+	}{k.IntID(), len(paths), invalid})
+}
+
+// queueJobTasks adds the refresh tasks of the job with the given ID and
+// returns the number of tasks added, which is less than the number of paths
+// if there is an error.
+func queueJobTasks(c context.Context, id int64, paths []string, opts lintOptions) (int, error) {
+	queued := 0
+	for i := 0; i < len(paths); i += maxTasksPerAdd {
+		end := i + maxTasksPerAdd
+		if end > len(paths) {
+			end = len(paths)
+		}
+		var tasks []*taskqueue.Task
+		for _, p := range paths[i:end] {
+			params := opts.Values()
+			params.Set("importPath", p)
+			params.Set("job", strconv.FormatInt(id, 10))
+			tasks = append(tasks, taskqueue.NewPOSTTask(refreshTaskPath, params))
+		}
+		if _, err := taskqueue.AddMulti(c, tasks, refreshQueue); err != nil {
+			return queued + countAdded(err), err
+		}
+		queued += len(tasks)
+	}
+	return queued, nil
+}
+
+// countAdded returns the number of tasks added by an AddMulti call that
+// returned err. Only an appengine.MultiError reports tasks that were added.
+func countAdded(err error) int {
+	me, ok := err.(appengine.MultiError)
+	if !ok {
+		return 0
+	}
+	n := 0
+	for _, err := range me {
+		if err == nil {
+			n++
+		}
+	}
+	return n
+}
+
+// serveJobStatus responds with the results of the job with the given id
+// so far.
+func serveJobStatus(w http.ResponseWriter, r *http.Request) error {
+	id, err := strconv.ParseInt(r.FormValue("id"), 10, 64)
+	if err != nil {
+		return &appError{Status: 400, Message: "Bad id parameter."}
+	}
+	c := appengine.NewContext(r)
+	var j job
+	if err := datastore.Get(c, jobKey(c, id), &j); err != nil {
+		if err == datastore.ErrNoSuchEntity {
+			err = &appError{Status: 404, Message: "Job not found."}
+		}
+		return err
+	}
+	status := &jobStatus{ID: id, Created: j.Created, Paths: j.Paths}
+	if _, err := datastore.NewQuery("JobResult").Ancestor(jobKey(c, id)).GetAll(c, &status.Results); err != nil {
+		return err
+	}
+	for _, res := range status.Results {
+		status.Completed++
+		if res.Outcome == "not_found" {
+			status.NotFound++
+		}
+		status.Problems += res.Problems
+	}
+	status.Done = status.Completed >= status.Paths
+	return writeJSONResponse(w, 200, status)
+}
+
+// putJobResult records the outcome of the refresh task for importPath in the
+// job with the given ID. The package is nil if it was not found. Its problems
+// are counted with the default confidence filter.
+func putJobResult(c context.Context, id int64, importPath string, pkg *lintPackage) error {
+	res := &jobResult{Path: importPath, Outcome: "not_found"}
+	if pkg != nil {
+		res.Outcome = "ok"
+		res.Problems, _ = pkg.counts()
+		res.Score = pkg.Score
+		res.Updated = pkg.Updated
+	}
+	_, err := datastore.Put(c, datastore.NewKey(c, "JobResult", importPath, 0, jobKey(c, id)), res)
+	return err
+}
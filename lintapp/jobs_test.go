@@ -0,0 +1,69 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package lintapp
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"google.golang.org/appengine"
+)
+
+func TestParseJobPaths(t *testing.T) {
+	const list = `# audit
+github.com/user/a
+
+  github.com/user/b  
+github.com/user/a
+example
+`
+	paths, invalid, err := parseJobPaths(strings.NewReader(list))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"github.com/user/a", "github.com/user/b"}; !reflect.DeepEqual(paths, want) {
+		t.Errorf("paths = %v, want %v", paths, want)
+	}
+	if want := []string{"example"}; !reflect.DeepEqual(invalid, want) {
+		t.Errorf("invalid = %v, want %v", invalid, want)
+	}
+}
+
+func TestServeAdminJobsTooLarge(t *testing.T) {
+	body := strings.Repeat("github.com/user/repo\n", maxJobPaths*(maxPathLength+1)/len("github.com/user/repo\n")+1)
+	for _, contentLength := range []bool{true, false} {
+		r, _ := http.NewRequest("POST", "/-/admin/jobs", strings.NewReader(body))
+		if !contentLength {
+			r.ContentLength = -1
+		}
+		err := serveAdminJobs(httptest.NewRecorder(), r)
+		if e, ok := err.(*appError); !ok || e.Status != 413 {
+			t.Errorf("serveAdminJobs with Content-Length %d returned %v, want 413 appError", r.ContentLength, err)
+		}
+	}
+}
+
+func TestCountAdded(t *testing.T) {
+	failed := errors.New("failed")
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{failed, 0},
+		{appengine.MultiError{nil, failed, nil}, 2},
+		{appengine.MultiError{failed, failed}, 0},
+	}
+	for _, tt := range tests {
+		if got := countAdded(tt.err); got != tt.want {
+			t.Errorf("countAdded(%v) = %d, want %d", tt.err, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package lintapp
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"google.golang.org/appengine"
+)
+
+// localRoot is the directory read by serveLocal.
+var localRoot = ""
+
+// serveLocal lints a directory below localRoot without fetching it from a
+// version control host. The result is not stored. Local linting is only
+// available on the development server.
+func serveLocal(w http.ResponseWriter, r *http.Request) error {
+	if !appengine.IsDevAppServer() || localRoot == "" {
+		return &appError{Status: 404, Message: "Local linting is not enabled."}
+	}
+	name := path.Clean("/" + r.FormValue("local"))[1:]
+	fis, err := ioutil.ReadDir(filepath.Join(localRoot, filepath.FromSlash(name)))
+	if os.IsNotExist(err) {
+		return &appError{Status: 404, Message: "Local directory not found."}
+	} else if err != nil {
+		return err
+	}
+	var files []*File
+	for _, fi := range fis {
+		if fi.IsDir() || !strings.HasSuffix(fi.Name(), ".go") {
+			continue
+		}
+		data, err := ioutil.ReadFile(filepath.Join(localRoot, filepath.FromSlash(name), fi.Name()))
+		if err != nil {
+			return err
+		}
+		files = append(files, &File{Name: fi.Name(), Data: data})
+	}
+	pkg := &lintPackage{Path: name, Updated: now()}
+	lintFiles(appengine.NewContext(r), pkg, files, nil)
+	setScore(pkg)
+	filterByConfidence(r, pkg)
+	return writeResponse(w, r, 200, packageTemplate, &packageView{lintPackage: pkg})
+}
@@ -0,0 +1,109 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package lintapp
+
+import (
+	"net/http"
+	"unicode/utf16"
+)
+
+// The types below are the subset of the Language Server Protocol used for
+// the lsp format. See
+// https://microsoft.github.io/language-server-protocol/specification.
+
+type lspPosition struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+type lspRange struct {
+	Start lspPosition `json:"start"`
+	End   lspPosition `json:"end"`
+}
+
+type lspDiagnostic struct {
+	Range    lspRange `json:"range"`
+	Severity int      `json:"severity"`
+	Code     string   `json:"code,omitempty"`
+	Source   string   `json:"source"`
+	Message  string   `json:"message"`
+}
+
+// LSP diagnostic severities.
+const (
+	lspError       = 1
+	lspWarning     = 2
+	lspInformation = 3
+	lspHint        = 4
+)
+
+// lspFile holds the diagnostics for one file. File is the repository relative
+// file name, and URL is the file on the source host.
+type lspFile struct {
+	File        string          `json:"file"`
+	URL         string          `json:"url,omitempty"`
+	Diagnostics []lspDiagnostic `json:"diagnostics"`
+}
+
+// lspSeverity maps a problem to a diagnostic severity. Lint errors are
+// errors, and problems are warnings, information or hints by confidence.
+func lspSeverity(p *lintProblem) int {
+	switch {
+	case p.Line == 0:
+		return lspError
+	case p.Confidence >= 0.8:
+		return lspWarning
+	case p.Confidence >= 0.5:
+		return lspInformation
+	default:
+		return lspHint
+	}
+}
+
+// lspDiagnostics converts the problems in pkg to diagnostics. Positions are
+// zero based. The range of a problem extends from its column to the end of
+// its line when the full line text is known. golint reports byte columns,
+// which are converted to LSP's UTF-16 characters using the line text. The
+// byte column is used as is if the line text is not known.
+func lspDiagnostics(pkg *lintPackage) []lspFile {
+	files := []lspFile{}
+	for _, f := range pkg.Files {
+		lf := lspFile{File: f.Name, URL: f.URL, Diagnostics: []lspDiagnostic{}}
+		for _, p := range f.Problems {
+			start := lspPosition{}
+			if p.Line > 0 {
+				start.Line = p.Line - 1
+			}
+			known := p.LineText != "" && !p.LineTextTruncated
+			if p.Column > 0 {
+				start.Character = p.Column - 1
+				if known && p.Column-1 <= len(p.LineText) {
+					start.Character = len(utf16.Encode([]rune(p.LineText[:p.Column-1])))
+				}
+			}
+			end := start
+			if known {
+				if n := len(utf16.Encode([]rune(p.LineText))); n > start.Character {
+					end.Character = n
+				}
+			}
+			lf.Diagnostics = append(lf.Diagnostics, lspDiagnostic{
+				Range:    lspRange{Start: start, End: end},
+				Severity: lspSeverity(p),
+				Code:     p.RuleID,
+				Source:   "golint",
+				Message:  p.Text,
+			})
+		}
+		files = append(files, lf)
+	}
+	return files
+}
+
+func writeLSPResponse(w http.ResponseWriter, pkg *lintPackage) error {
+	return writeJSONResponse(w, 200, lspDiagnostics(pkg))
+}
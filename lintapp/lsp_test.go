@@ -0,0 +1,36 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package lintapp
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLSPDiagnostics(t *testing.T) {
+	pkg := &lintPackage{Files: []*lintFile{{
+		Name: "a.go",
+		Problems: []*lintProblem{
+			{Line: 3, Column: 6, Text: "exported F should have comment", LineText: "func F() {}", Confidence: 1, RuleID: "exported-comment"},
+			{Line: 5, Column: 2, Text: "low", LineText: "x", Confidence: 0.3},
+			{Line: 7, Column: 15, Text: "after a string", LineText: "s := \"é😀\" + t", Confidence: 1},
+			{Text: "parse error"},
+		},
+	}}}
+	want := []lspFile{{
+		File: "a.go",
+		Diagnostics: []lspDiagnostic{
+			{Range: lspRange{lspPosition{2, 5}, lspPosition{2, 11}}, Severity: lspWarning, Code: "exported-comment", Source: "golint", Message: "exported F should have comment"},
+			{Range: lspRange{lspPosition{4, 1}, lspPosition{4, 1}}, Severity: lspHint, Source: "golint", Message: "low"},
+			{Range: lspRange{lspPosition{6, 11}, lspPosition{6, 14}}, Severity: lspWarning, Source: "golint", Message: "after a string"},
+			{Severity: lspError, Source: "golint", Message: "parse error"},
+		},
+	}}
+	if got := lspDiagnostics(pkg); !reflect.DeepEqual(got, want) {
+		t.Errorf("lspDiagnostics = %+v, want %+v", got, want)
+	}
+}
@@ -9,20 +9,31 @@ package lintapp
 
 import (
 	"bytes"
-	"encoding/gob"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"go/parser"
+	"go/token"
 	"html/template"
+	"net"
 	"net/http"
+	"net/url"
 	"os"
+	"path"
 	"path/filepath"
+	"regexp"
+	"runtime/debug"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"golang.org/x/net/context"
 	"google.golang.org/appengine"
-	"google.golang.org/appengine/datastore"
 	"google.golang.org/appengine/log"
+	"google.golang.org/appengine/memcache"
 	"google.golang.org/appengine/urlfetch"
 
 	"github.com/ReturnPath/gddo/gosrc"
@@ -34,80 +45,284 @@ import (
 func init() {
 	http.Handle("/", handlerFunc(serveRoot))
 	http.Handle("/-/bot", handlerFunc(serveBot))
+	http.Handle("/-/version", handlerFunc(serveVersion))
+	http.Handle("/-/selftest", handlerFunc(serveSelfTest))
+	http.Handle("/robots.txt", handlerFunc(serveRobots))
 	http.Handle("/-/refresh", handlerFunc(serveRefresh))
-	if s := os.Getenv("CONTACT_EMAIL"); s != "" {
-		contactEmail = s
+	http.Handle("/-/batch", handlerFunc(serveBatch))
+	http.Handle(refreshTaskPath, handlerFunc(serveRefreshTask))
+	http.Handle(eventsTaskPath, handlerFunc(serveEventsTask))
+	http.Handle("/-/admin/raw", adminHandlerFunc(serveAdminRaw))
+	http.Handle("/-/admin/baseline", adminHandlerFunc(serveAdminBaseline))
+	http.Handle("/-/admin/audit", adminHandlerFunc(serveAdminAudit))
+	http.Handle("/-/admin/jobs", adminHandlerFunc(serveAdminJobs))
+	http.Handle("/-/admin/note", adminHandlerFunc(serveAdminNote))
+	http.Handle("/-/admin/migrate", adminHandlerFunc(serveAdminMigrate))
+	http.Handle("/-/against-baseline", handlerFunc(serveAgainstBaseline))
+	http.Handle("/-/since", handlerFunc(serveSince))
+	http.Handle("/-/compare", handlerFunc(serveCompare))
+	http.Handle("/-/preview", handlerFunc(servePreview))
+	http.Handle("/-/save", handlerFunc(serveSave))
+	http.Handle("/-/rules", handlerFunc(serveRules))
+	http.Handle("/v/", handlerFunc(serveView))
+	http.HandleFunc("/_ah/stop", serveStop)
+	envString("CONTACT_EMAIL", &contactEmail)
+	envString("ABUSE_EMAIL", &abuseEmail)
+	envString("STATUS_URL", &statusURL)
+	envString("SOURCE_URL", &sourceURL)
+	envString("FOOTER", &footerText)
+	envString("NOINDEX", &noindexMode)
+	envInt("CRAWL_DELAY", &crawlDelay)
+	envString("HOME_REDIRECT", &homeRedirect)
+	envString("BOT_URL", &botURL)
+	envString("LOCAL_ROOT", &localRoot)
+	envDuration("PACKAGE_TTL", &packageTTL)
+	envDuration("VERSION_GRACE", &versionGrace)
+	envDuration("DRAIN_TIMEOUT", &drainTimeout)
+	confidenceOverrides = parseConfidenceOverrides(os.Getenv("MIN_CONFIDENCE_OVERRIDES"))
+	lineFmtOverrides = parseLineFmtOverrides(os.Getenv("LINE_FMT_OVERRIDES"))
+	callbackHosts = parseCallbackHosts(os.Getenv("CALLBACK_HOSTS"))
+	volatileTextPatterns = append(volatileTextPatterns, parseTextPatterns(os.Getenv("VOLATILE_TEXT_PATTERNS"))...)
+	if os.Getenv("STORE") == "memory" {
+		store = newMemoryStore()
+	}
+	compactStore = os.Getenv("COMPACT_STORE") == "1"
+	if s := os.Getenv("BIGQUERY_TABLE"); s != "" {
+		analytics = &bigQueryAnalytics{Dataset: os.Getenv("BIGQUERY_DATASET"), Table: s}
+	}
+	if os.Getenv("AUDIT_LOG") == "1" {
+		analytics = multiAnalytics{analytics, auditAnalytics{}}
+	}
+	envInt("MAX_LINE_TEXT", &maxLineText)
+	envInt("MAX_LINT_RUNS", &maxLintRuns)
+	envInt("MAX_NOCACHE_RUNS", &maxNoCacheRuns)
+	envInt("MAX_CACHED_PACKAGES", &maxCachedPackages)
+	envDuration("CACHED_PACKAGE_TTL", &cachedPackageTTL)
+	envInt("MAX_PATH_LENGTH", &maxPathLength)
+	envInt("MAX_PATH_SEGMENTS", &maxPathSegments)
+	envInt("WARN_PROBLEM_COUNT", &warnProblemCount)
+	envInt("WARN_LOW_CONFIDENCE_COUNT", &warnLowConfidenceCount)
+	envInt("REGRESSION_THRESHOLD", &regressionThreshold)
+	envInt("COLLAPSE_FILE_COUNT", &collapseFileCount)
+	envInt("MAX_FILES_PER_PACKAGE", &maxFilesPerPackage)
+	requireLogin = os.Getenv("REQUIRE_LOGIN") == "1"
+	countParseErrors = os.Getenv("COUNT_PARSE_ERRORS") == "1"
+	envString("AUTH_SECRET", &authSecret)
+	hotPackages = newPackageCache(maxCachedPackages, cachedPackageTTL)
+	if maxLintRuns > 0 {
+		lintSem = make(chan struct{}, maxLintRuns)
+	}
+}
+
+// envString sets *v to the value of the environment variable name, if the
+// variable is not empty.
+func envString(name string, v *string) {
+	if s := os.Getenv(name); s != "" {
+		*v = s
+	}
+}
+
+// envDuration sets *v to the value of the environment variable name, if the
+// variable is set to a duration.
+func envDuration(name string, v *time.Duration) {
+	if d, err := time.ParseDuration(os.Getenv(name)); err == nil {
+		*v = d
+	}
+}
+
+// envInt sets *v to the value of the environment variable name, if the
+// variable is set to an integer.
+func envInt(name string, v *int) {
+	if n, err := strconv.Atoi(os.Getenv(name)); err == nil {
+		*v = n
 	}
 }
 
 var (
-	contactEmail    = "golang-dev@googlegroups.com"
-	homeTemplate    = parseTemplate("common.html", "index.html")
-	packageTemplate = parseTemplate("common.html", "package.html")
-	errorTemplate   = parseTemplate("common.html", "error.html")
-	templateFuncs   = template.FuncMap{
-		"timeago":      timeagoFn,
+	contactEmail      = "golang-dev@googlegroups.com"
+	abuseEmail        = ""
+	statusURL         = ""
+	sourceURL         = "https://github.com/golang/gddo"
+	homeRedirect      = ""
+	botURL            = ""
+	footerText        = ""
+	packageTTL        = 24 * time.Hour
+	versionGrace      time.Duration
+	maxLineText       = 200
+	maxLintRuns       = 8
+	maxNoCacheRuns    = 5
+	maxCachedPackages = 100
+	maxPathLength     = 200
+	maxPathSegments   = 16
+	warnProblemCount  = 50
+	collapseFileCount = 5
+	hotPackages       *packageCache
+	homeTemplate      = parseTemplate("common.html", "index.html")
+	packageTemplate   = parseTemplate("common.html", "package.html")
+	errorTemplate     = parseTemplate("common.html", "error.html")
+	templateFuncs     = template.FuncMap{
+		"timestamp":    timestampFn,
 		"contactEmail": contactEmailFn,
+		"asset":        assetFn,
+		"footer":       footerFn,
+		"buildVersion": buildVersionFn,
+		"stars":        starsFn,
 	}
 	github = httputil.NewAuthTransportFromEnvironment(nil)
 )
 
+//go:generate go run gentemplates.go -output templates_embedded.go
+
+// parseTemplate parses the named files in assets/templates and returns their
+// ROOT template. It panics on errors. The files are read from
+// embeddedTemplates when the server is built with the embedtemplates tag, and
+// from disk otherwise.
 func parseTemplate(fnames ...string) *template.Template {
+	if embeddedTemplates != nil {
+		t, err := parseEmbeddedTemplates(fnames)
+		if err != nil {
+			panic(err)
+		}
+		return t
+	}
 	paths := make([]string, len(fnames))
 	for i := range fnames {
 		paths[i] = filepath.Join("assets/templates", fnames[i])
 	}
-	t, err := template.New("").Funcs(templateFuncs).ParseFiles(paths...)
+	t, err := parseTemplateFiles(paths)
 	if err != nil {
 		panic(err)
 	}
+	templateSources[t] = &templateSource{paths: paths, modTime: time.Now(), t: t}
+	return t
+}
+
+// parseTemplateFiles parses the files and returns their ROOT template.
+func parseTemplateFiles(paths []string) (*template.Template, error) {
+	// The locale functions are replaced with those of the request locale in
+	// writeResponse.
+	t, err := template.New("").Funcs(templateFuncs).Funcs(defaultLocale.funcs()).ParseFiles(paths...)
+	if err != nil {
+		return nil, err
+	}
 	t = t.Lookup("ROOT")
 	if t == nil {
-		panic(fmt.Sprintf("ROOT template not found in %v", fnames))
+		return nil, fmt.Errorf("ROOT template not found in %v", paths)
 	}
-	return t
+	return t, nil
+}
+
+// parseEmbeddedTemplates is parseTemplateFiles for the embedded templates
+// with the given names.
+func parseEmbeddedTemplates(fnames []string) (*template.Template, error) {
+	t := template.New("").Funcs(templateFuncs).Funcs(defaultLocale.funcs())
+	for _, name := range fnames {
+		text, ok := embeddedTemplates[name]
+		if !ok {
+			return nil, fmt.Errorf("template %s not embedded", name)
+		}
+		if _, err := t.New(name).Parse(text); err != nil {
+			return nil, err
+		}
+	}
+	root := t.Lookup("ROOT")
+	if root == nil {
+		return nil, fmt.Errorf("ROOT template not found in %v", fnames)
+	}
+	return root, nil
 }
 
 func contactEmailFn() string {
 	return contactEmail
 }
 
-func timeagoFn(t time.Time) string {
-	d := time.Since(t)
-	switch {
-	case d < time.Second:
-		return "just now"
-	case d < 2*time.Second:
-		return "one second ago"
-	case d < time.Minute:
-		return fmt.Sprintf("%d seconds ago", d/time.Second)
-	case d < 2*time.Minute:
-		return "one minute ago"
-	case d < time.Hour:
-		return fmt.Sprintf("%d minutes ago", d/time.Minute)
-	case d < 2*time.Hour:
-		return "one hour ago"
-	case d < 48*time.Hour:
-		return fmt.Sprintf("%d hours ago", d/time.Hour)
-	default:
-		return fmt.Sprintf("%d days ago", d/(time.Hour*24))
+// timestampFn formats t as an RFC 3339 timestamp in loc, or in UTC if loc
+// is nil.
+func timestampFn(t time.Time, loc *time.Location) string {
+	if loc == nil {
+		loc = time.UTC
 	}
+	return t.In(loc).Format(time.RFC3339)
 }
 
-func writeResponse(w http.ResponseWriter, status int, t *template.Template, v interface{}) error {
+// writeResponse executes t with the strings of the request locale. The parsed
+// templates are only executed through clones so that the functions can be
+// replaced. The output is buffered, so nothing is written if execution fails
+// and the handler serves the error page for the returned error instead.
+func writeResponse(w http.ResponseWriter, r *http.Request, status int, t *template.Template, v interface{}) error {
+	l := requestLocale(r)
+	if reloadTemplates() {
+		var err error
+		if t, err = reloadTemplate(t); err != nil {
+			return err
+		}
+	}
+	t, err := t.Clone()
+	if err != nil {
+		return err
+	}
 	var buf bytes.Buffer
-	if err := t.Execute(&buf, v); err != nil {
+	if err := t.Funcs(l.funcs()).Execute(&buf, v); err != nil {
 		return err
 	}
+	w.Header().Set("Content-Language", l.Tag)
+	w.Header().Add("Vary", "Accept-Language")
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 	w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
 	w.WriteHeader(status)
-	_, err := w.Write(buf.Bytes())
+	_, err = w.Write(buf.Bytes())
 	return err
 }
 
-func writeErrorResponse(w http.ResponseWriter, status int) error {
-	return writeResponse(w, status, errorTemplate, http.StatusText(status))
+// appError is an error with the HTTP status and message shown to the user.
+// Handlers return an *appError for expected failures.
+type appError struct {
+	Status int
+
+	// Message is shown to the user. It is optional.
+	Message string
+
+	// Detail is additional information shown in small print. It is
+	// optional.
+	Detail string
+}
+
+func (e *appError) Error() string {
+	s := http.StatusText(e.Status)
+	if e.Message != "" {
+		s += ": " + e.Message
+	}
+	return s
+}
+
+// Title returns the title of the error page.
+func (e *appError) Title() string {
+	return http.StatusText(e.Status)
+}
+
+func writeErrorResponse(w http.ResponseWriter, r *http.Request, e *appError) error {
+	return writeResponse(w, r, e.Status, errorTemplate, e)
+}
+
+// defaultUserAgent is the User-Agent template used when the USER_AGENT
+// environment variable is not set.
+const defaultUserAgent = "{app} (+{bot})"
+
+// userAgent returns the User-Agent for requests to source hosts. The
+// USER_AGENT environment variable is a template in which {app} is replaced
+// with the application ID and {bot} with the URL of the bot information page.
+// The bot information page defaults to /-/bot on the host serving r and can
+// be set with BOT_URL for deployments with a custom domain or page.
+func userAgent(c context.Context, r *http.Request) string {
+	bot := botURL
+	if bot == "" {
+		bot = "http://" + r.Host + "/-/bot"
+	}
+	t := github.UserAgent
+	if t == "" {
+		t = defaultUserAgent
+	}
+	return strings.NewReplacer("{app}", appengine.AppID(c), "{bot}", bot).Replace(t)
 }
 
 func httpClient(r *http.Request) *http.Client {
@@ -118,16 +333,29 @@ func httpClient(r *http.Request) *http.Client {
 			ClientID:     github.ClientID,
 			ClientSecret: github.ClientSecret,
 			Base:         &urlfetch.Transport{Context: c, Deadline: 10 * time.Second},
-			UserAgent:    fmt.Sprintf("%s (+http://%s/-/bot)", appengine.AppID(c), r.Host),
+			UserAgent:    userAgent(c, r),
 		},
 	}
 }
 
-const version = 1
+// version is the version of the stored packages. Add an entry to
+// migrations when a change only adds fields.
+const version = 19
+
+// linterVersion identifies the version of golint producing the results. It
+// is the revision of github.com/golang/lint in Godeps.json and must be
+// updated with it.
+const linterVersion = "3390df4df2787994aea98de825b964ac7944b817"
 
 type storePackage struct {
 	Data    []byte
 	Version int
+
+	// Path and Problems are set on the Package entities of results linted
+	// with the default options, for listing the packages of a repository.
+	// Problems counts the problems shown by default.
+	Path     string
+	Problems int
 }
 
 type lintPackage struct {
@@ -136,107 +364,795 @@ type lintPackage struct {
 	Updated time.Time
 	LineFmt string
 	URL     string
+	Options lintOptions
+
+	// LinterVersion is the linterVersion of the linter producing the
+	// result. LinterChanged is true if the lint run producing the result
+	// replaced one stored with a different linter or store version, so
+	// that problem counts may have changed for reasons other than changes
+	// to the package. It is only set on the response of that run and is
+	// not stored, so the notice is not shown on later views.
+	LinterVersion string
+	LinterChanged bool
+
+	// IsCommand is true if the package is a main package.
+	IsCommand bool
+
+	// Unanalyzed lists the files with parse errors not counted as
+	// problems. It is only set for responses, by separateParseErrors.
+	Unanalyzed []unanalyzedFile `json:",omitempty"`
+
+	// Packages lists the package names in the directory if there is more
+	// than one, ignoring external test packages and files excluded from
+	// all builds with an ignore build constraint.
+	Packages []string
+
+	// Generated lists the generated files that were not linted.
+	Generated []string
+
+	// Unfetched lists the Go files that were not linted because their
+	// contents could not be retrieved from the source host.
+	Unfetched []string
+
+	// Unlinted is the number of Go files that were not linted because the
+	// package has more than maxFilesPerPackage files.
+	Unlinted int
+
+	// Deprecated is true if the package is deprecated, as explained by
+	// DeprecationNote.
+	Deprecated      bool
+	DeprecationNote string
+
+	// Hashes maps the names of the linted files, with no directory, to
+	// hashes of their contents. Unchanged files are not linted again on
+	// refresh.
+	Hashes map[string]string
+
+	// Lines is the number of lines of code in the linted files. Score
+	// summarizes the problem density, as computed by setScore.
+	Lines int
+	Score float64
+
+	// Compact is true if the source lines of the problems were dropped to
+	// store the package, as done by compactPackage.
+	Compact bool
+
+	// stale is true if the package was stored with an older version and
+	// is served during the version grace window. It is not stored.
+	stale bool
 }
 
 type lintFile struct {
+	// Name is the path of the file relative to the repository root.
 	Name     string
 	Problems []*lintProblem
 	URL      string
 }
 
+// AllURL returns the URL of the package page with all files linted.
+func (pkg *lintPackage) AllURL() string {
+	opts := pkg.Options
+	opts.All = true
+	return opts.pageURL(pkg.Path, "")
+}
+
+// PageURL returns the URL of the package page for pkg with suffix appended to
+// the path.
+func (pkg *lintPackage) PageURL(suffix string) string {
+	return pkg.Options.pageURL(pkg.Path, suffix)
+}
+
+// GeneratedURL returns the URL of the package page with generated files
+// linted.
+func (pkg *lintPackage) GeneratedURL() string {
+	opts := pkg.Options
+	opts.Generated = true
+	return opts.pageURL(pkg.Path, "")
+}
+
+// byProblemCount sorts files by descending number of problems.
+type byProblemCount []*lintFile
+
+func (s byProblemCount) Len() int           { return len(s) }
+func (s byProblemCount) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s byProblemCount) Less(i, j int) bool { return len(s[i].Problems) > len(s[j].Problems) }
+
+// packageView is the data for the package template.
+type packageView struct {
+	*lintPackage
+	Location *time.Location
+
+	// Permalink is true if the view shows a snapshot requested by permalink.
+	Permalink bool
+
+	// SnapshotExpired is true if the snapshot requested by permalink was
+	// not found and the view shows the current result instead.
+	SnapshotExpired bool
+
+	// Rule is the rule selected in the URL path, or nil if problems are not
+	// filtered by rule.
+	Rule *rule
+
+	// API is true if only the problems selected by api=1 are shown.
+	API bool
+
+	// File is the name of the file selected in the URL path, or "" if
+	// problems are not filtered by file.
+	File string
+
+	// Cleaned lists the files that had problems in earlier results and have
+	// none now. It is only set for unfiltered current results.
+	Cleaned []cleanedFile
+
+	// ExcludeFiles lists the patterns of the files excluded with the
+	// excludeFiles parameter.
+	ExcludeFiles []string
+
+	// RefreshPending is true if a queued refresh of the package has not
+	// finished.
+	RefreshPending bool
+
+	// ShareURL is the absolute URL of the page, used in link preview meta
+	// tags.
+	ShareURL string
+
+	// Flat is true if the problems are shown as one list across files,
+	// sorted by descending confidence if SortByConfidence is set.
+	Flat             bool
+	SortByConfidence bool
+
+	// CollapseFiles is true if the files are shown collapsed to their
+	// headers. Files are collapsed when there are more than
+	// collapseFileCount of them, unless expand=all is set.
+	CollapseFiles bool
+
+	// Note is the operator note on the package, or nil if it has none. It
+	// is only set for HTML pages.
+	Note *packageNote
+
+	// Similar lists other stored packages of the same repository. It is
+	// only set for HTML pages.
+	Similar []*packageSummary
+}
+
+// HighProblemCount reports whether the number of problems shown is above
+// warnProblemCount.
+func (v *packageView) HighProblemCount() bool {
+	problems, _ := v.counts()
+	return warnProblemCount > 0 && problems > warnProblemCount
+}
+
+// LowConfidenceCount returns the number of problems with confidence below
+// lowConfidence shown, if it is above warnLowConfidenceCount, or zero.
+func (v *packageView) LowConfidenceCount() int {
+	if warnLowConfidenceCount <= 0 {
+		return 0
+	}
+	if n := lowConfidenceCount(v.lintPackage); n > warnLowConfidenceCount {
+		return n
+	}
+	return 0
+}
+
+// Description returns a one sentence summary of the problems shown on the
+// page, used in link preview meta tags.
+func (v *packageView) Description() string {
+	problems, files := v.counts()
+	if problems == 0 {
+		return fmt.Sprintf("golint found no problems in %s.", v.Path)
+	}
+	return fmt.Sprintf("golint found %s in %s in %s.", plural(problems, "problem", "problems"), plural(files, "file", "files"), v.Path)
+}
+
 type lintProblem struct {
-	Line       int
-	Text       string
-	LineText   string
-	Confidence float64
-	Link       string
+	Line              int
+	Column            int
+	Offset            int
+	Text              string
+	LineText          string
+	LineTextTruncated bool
+	Confidence        float64
+	Link              string
+	RuleID            string
+
+	// NormalizedText is Text without volatile parts, such as positions,
+	// for matching the problem in other revisions of the package.
+	NormalizedText string
+
+	// ParseError is true if the problem is the error parsing the file
+	// rather than a lint problem. See separateParseErrors.
+	ParseError bool
+
+	// SuggestedFix is the replacement implied by the problem text, such as
+	// the name an identifier should be renamed to. It is empty if the fix
+	// cannot be inferred.
+	SuggestedFix string
+
+	// Before and After are the source lines around the problem line. They
+	// are stored for up to maxContextLines lines and trimmed to the number
+	// of lines requested with the context parameter.
+	Before []string `json:",omitempty"`
+	After  []string `json:",omitempty"`
+
+	// BelowThreshold is true if the problem's confidence is below the
+	// minimum and it is shown collapsed. It is only set for display.
+	BelowThreshold bool `json:"-"`
 }
 
-func putPackage(c context.Context, importPath string, pkg *lintPackage) error {
-	var buf bytes.Buffer
-	if err := gob.NewEncoder(&buf).Encode(pkg); err != nil {
+// ProblemCount returns the number of problems in f that are not shown
+// collapsed.
+func (f *lintFile) ProblemCount() int {
+	n := 0
+	for _, p := range f.Problems {
+		if !p.BelowThreshold {
+			n++
+		}
+	}
+	return n
+}
+
+// Collapsed returns the problems in f shown collapsed.
+func (f *lintFile) Collapsed() []*lintProblem {
+	var problems []*lintProblem
+	for _, p := range f.Problems {
+		if p.BelowThreshold {
+			problems = append(problems, p)
+		}
+	}
+	return problems
+}
+
+// FlatProblems returns the problems not shown collapsed in all files, for
+// the flat view.
+func (v *packageView) FlatProblems() []*problemItem {
+	var items []*problemItem
+	for _, f := range v.Files {
+		for _, p := range f.Problems {
+			if !p.BelowThreshold {
+				items = append(items, v.Item(f, p))
+			}
+		}
+	}
+	if v.SortByConfidence {
+		sort.Stable(byConfidence(items))
+	}
+	return items
+}
+
+// byConfidence sorts problems by descending confidence.
+type byConfidence []*problemItem
+
+func (s byConfidence) Len() int           { return len(s) }
+func (s byConfidence) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s byConfidence) Less(i, j int) bool { return s[i].Confidence > s[j].Confidence }
+
+// problemItem is the data for the problem template.
+type problemItem struct {
+	*lintProblem
+	File *lintFile
+
+	// URL is the problem's line on the source host, as from problemURL.
+	URL string
+}
+
+// Item returns the data for showing problem p in file f.
+func (v *packageView) Item(f *lintFile, p *lintProblem) *problemItem {
+	return &problemItem{lintProblem: p, File: f, URL: problemURL(v.lintPackage, f, p)}
+}
+
+// truncateLineText shortens s to at most n runes around the byte column col,
+// replacing the text cut from either end with an ellipsis. A col of 0 keeps
+// the start of the line. Lines in generated or minified code can be
+// arbitrarily long, and the problem may be anywhere in them.
+func truncateLineText(s string, col, n int) (string, bool) {
+	if n <= 0 || utf8.RuneCountInString(s) <= n {
+		return s, false
+	}
+	if n < 3 {
+		// No room for an ellipsis at both ends.
+		col = 0
+	}
+	// offsets holds the byte offset of each rune, then len(s).
+	var offsets []int
+	k := 0 // index of the rune at col
+	for i := range s {
+		if i < col {
+			k = len(offsets)
+		}
+		offsets = append(offsets, i)
+	}
+	total := len(offsets)
+	offsets = append(offsets, len(s))
+
+	start := k - n/2
+	if start < 0 {
+		start = 0
+	}
+	if start > total-n {
+		start = total - n
+	}
+	end := start + n
+	head, tail := "", ""
+	if start > 0 {
+		start++
+		head = "…"
+	}
+	if end < total {
+		end--
+		tail = "…"
+	}
+	return head + s[offsets[start]:offsets[end]] + tail, true
+}
+
+// storedPackage returns a shallow copy of pkg without the fields that
+// describe the lint run rather than the result.
+func storedPackage(pkg *lintPackage) *lintPackage {
+	p := *pkg
+	p.LinterChanged = false
+	return &p
+}
+
+func putPackage(c context.Context, key string, pkg *lintPackage) error {
+	pkg = storedPackage(pkg)
+	if err := store.Put(c, key, pkg); err != nil {
+		hotPackages.remove(key)
 		return err
 	}
-	_, err := datastore.Put(c,
-		datastore.NewKey(c, "Package", importPath, 0, nil),
-		&storePackage{Data: buf.Bytes(), Version: version})
-	return err
+	hotPackages.add(key, pkg)
+	return nil
 }
 
-func getPackage(c context.Context, importPath string) (*lintPackage, error) {
-	var spkg storePackage
-	if err := datastore.Get(c, datastore.NewKey(c, "Package", importPath, 0, nil), &spkg); err != nil {
-		if err == datastore.ErrNoSuchEntity {
-			err = nil
+// putPackages stores pkgs[i] under keys[i] with Store.PutMulti.
+func putPackages(c context.Context, keys []string, pkgs []*lintPackage) error {
+	stored := make([]*lintPackage, len(pkgs))
+	for i, pkg := range pkgs {
+		stored[i] = storedPackage(pkg)
+	}
+	pkgs = stored
+	if err := store.PutMulti(c, keys, pkgs); err != nil {
+		for _, key := range keys {
+			hotPackages.remove(key)
 		}
-		return nil, err
+		return err
+	}
+	for i, key := range keys {
+		hotPackages.add(key, pkgs[i])
+	}
+	return nil
+}
+
+// getPackage returns the package stored under key. A package stored with a
+// different version is returned, marked stale, only within versionGrace of
+// its last update; otherwise it is treated as missing so that it is linted
+// again.
+func getPackage(c context.Context, key string) (*lintPackage, error) {
+	pkg, err := getAnyVersionPackage(c, key)
+	if pkg != nil && pkg.stale && !withinVersionGrace(pkg) {
+		pkg = nil
+	}
+	return pkg, err
+}
+
+// getAnyVersionPackage is like getPackage, but also returns packages stored
+// with a different version after versionGrace.
+func getAnyVersionPackage(c context.Context, key string) (*lintPackage, error) {
+	if pkg := hotPackages.get(key); pkg != nil {
+		return pkg, nil
+	}
+	pkg, err := store.Get(c, key)
+	if pkg != nil && !pkg.stale {
+		hotPackages.add(key, pkg)
+	}
+	return pkg, err
+}
+
+// withinVersionGrace reports whether pkg, stored with a different version,
+// was updated recently enough to be served while it is linted again.
+func withinVersionGrace(pkg *lintPackage) bool {
+	return versionGrace > 0 && now().Sub(pkg.Updated) <= versionGrace
+}
+
+// now returns the current time for lint results and their display. It is a
+// variable for testing.
+var now = time.Now
+
+// lintSource lints a single file. It is a variable for testing.
+var lintSource = func(filename string, src []byte) ([]lint.Problem, error) {
+	linter := lint.Linter{}
+	return linter.Lint(filename, src)
+}
+
+// linterPanic is the error returned by safeLint when the linter panics.
+type linterPanic struct {
+	value interface{}
+	stack []byte
+}
+
+func (e *linterPanic) Error() string {
+	return "golint crashed analyzing this file"
+}
+
+// safeLint calls lintSource, recovering from a panic in the linter so that
+// one bad file does not take down the lint run.
+func safeLint(filename string, src []byte) (problems []lint.Problem, err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			problems = nil
+			err = &linterPanic{value: v, stack: debug.Stack()}
+		}
+	}()
+	return lintSource(filename, src)
+}
+
+// lintSem limits the number of concurrent lint runs on the instance. A nil
+// lintSem does not limit lint runs.
+var lintSem chan struct{}
+
+// lintQueueTimeout is how long a lint run waits for a free slot in lintSem.
+const lintQueueTimeout = 2 * time.Second
+
+// acquireLintSlot waits up to lintQueueTimeout for a free slot in lintSem.
+// It returns the function releasing the slot, or errBusy.
+func acquireLintSlot() (func(), error) {
+	if lintSem == nil {
+		return func() {}, nil
 	}
-	if spkg.Version != version {
-		return nil, nil
+	select {
+	case lintSem <- struct{}{}:
+		return func() { <-lintSem }, nil
+	case <-time.After(lintQueueTimeout):
+		return nil, errBusy
 	}
-	var pkg lintPackage
-	if err := gob.NewDecoder(bytes.NewReader(spkg.Data)).Decode(&pkg); err != nil {
+}
+
+// errBusy is returned by runLint when the instance is running the maximum
+// number of concurrent lint runs or is being stopped.
+var errBusy = errors.New("too many lint runs in progress")
+
+// lintRuns coalesces concurrent runLint calls for the same package.
+var lintRuns lintGroup
+
+// runLint fetches, lints and stores the package with the given import path.
+// Concurrent calls for the same package and options share one lint run.
+func runLint(r *http.Request, importPath string, opts lintOptions) (*lintPackage, error) {
+	return runLintBatch(r, importPath, opts, nil)
+}
+
+// runLintBatch is like runLint, but if b is not nil, the result is added to
+// b to be stored by b.flush instead of stored by the lint run. Such runs are
+// not coalesced with others, which would get a result not yet stored.
+func runLintBatch(r *http.Request, importPath string, opts lintOptions, b *packageBatch) (*lintPackage, error) {
+	var pkg *lintPackage
+	var err error
+	if b != nil {
+		pkg, err = lintAndStore(r, importPath, opts, b)
+	} else {
+		pkg, err = lintRuns.do(opts.key(importPath), func() (*lintPackage, error) {
+			return lintAndStore(r, importPath, opts, nil)
+		})
+	}
+	if err != nil {
 		return nil, err
 	}
-	return &pkg, nil
+	return pkg.clone(), nil
 }
 
-func runLint(r *http.Request, importPath string) (*lintPackage, error) {
-	dir, err := gosrc.Get(httpClient(r), importPath, "")
+func lintAndStore(r *http.Request, importPath string, opts lintOptions, b *packageBatch) (*lintPackage, error) {
+	if !lintsInFlight.start() {
+		return nil, errBusy
+	}
+	defer lintsInFlight.done()
+
+	release, err := acquireLintSlot()
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	c := appengine.NewContext(r)
+	dir, err := fetchPackage(withHTTPClient(c, httpClient(r)), importPath, "")
 	if err != nil {
 		return nil, err
 	}
+	// Lint and store a moved package under the canonical path.
+	importPath = dir.ImportPath
+	if !hasGoFiles(dir.Files) {
+		return nil, noPackageError{importPath}
+	}
 
 	pkg := lintPackage{
 		Path:    importPath,
-		Updated: time.Now(),
-		LineFmt: dir.LineFmt,
+		Updated: now(),
+		LineFmt: lineFmt(importPath, dir.LineFmt),
 		URL:     dir.BrowseURL,
+		Options: opts,
+
+		LinterVersion: linterVersion,
 	}
-	linter := lint.Linter{}
-	for _, f := range dir.Files {
-		if !strings.HasSuffix(f.Name, ".go") {
+	prev, err := getAnyVersionPackage(c, opts.key(importPath))
+	if err != nil {
+		log.Warningf(c, "Getting previous result for %s: %v", importPath, err)
+	}
+	if linterChanged(prev) {
+		// Results of another linter cannot be reused.
+		pkg.LinterChanged = true
+		prev = nil
+	}
+	lintFiles(c, &pkg, dir.Files, prev)
+	setScore(&pkg)
+	checkDeprecated(&pkg, dir.Files)
+	setRepoRelativeNames(&pkg, strings.Trim(strings.TrimPrefix(importPath, dir.ProjectRoot), "/"))
+	if b != nil {
+		b.add(opts.key(importPath), &pkg)
+	} else if err := putPackage(c, opts.key(importPath), &pkg); err != nil {
+		return nil, err
+	}
+
+	return &pkg, nil
+}
+
+// linterChanged reports whether prev, the previous result for a package, was
+// produced with a different linter or store version.
+func linterChanged(prev *lintPackage) bool {
+	return prev != nil && (prev.stale || prev.LinterVersion != linterVersion)
+}
+
+// maxFilesPerPackage is the maximum number of files linted in a package,
+// bounding the cost of a lint run. Zero means no limit.
+var maxFilesPerPackage = 200
+
+// lintFiles lints the Go files in files and adds the files with problems to
+// pkg. Unless pkg.Options.All is set, files after the first
+// maxFilesPerPackage in name order are counted in pkg.Unlinted instead. The
+// results in prev are reused for unchanged files unless prev is compact.
+func lintFiles(c context.Context, pkg *lintPackage, files []*File, prev *lintPackage) {
+	if prev != nil && prev.Compact {
+		// The source lines of compact results cannot be reused.
+		prev = nil
+	}
+	files = append([]*File(nil), files...)
+	sort.Sort(filesByName(files))
+	linted := 0
+	contextBudget := maxContextBytes
+	prevFiles := make(map[string]*lintFile)
+	if prev != nil {
+		for _, f := range prev.Files {
+			prevFiles[path.Base(f.Name)] = f
+		}
+	}
+	pkg.Hashes = make(map[string]string)
+	names := make(map[string]bool)
+	for _, f := range files {
+		if !strings.HasSuffix(f.Name, ".go") || !pkg.Options.matchFile(f) {
+			continue
+		}
+		if len(f.Data) == 0 {
+			pkg.Unfetched = append(pkg.Unfetched, f.Name)
+			continue
+		}
+		name := packageName(f.Data)
+		if !strings.HasSuffix(f.Name, "_test.go") && name == "main" {
+			pkg.IsCommand = true
+		}
+		if name != "" && !ignoredPat.Match(f.Data) {
+			names[strings.TrimSuffix(name, "_test")] = true
+		}
+		if !pkg.Options.Generated && generatedPat.Match(f.Data) {
+			pkg.Generated = append(pkg.Generated, f.Name)
+			continue
+		}
+		if !pkg.Options.All && maxFilesPerPackage > 0 && linted >= maxFilesPerPackage {
+			pkg.Unlinted++
+			continue
+		}
+		linted++
+		pkg.Lines += codeLines(f.Data)
+		hash := fileHash(f.Data)
+		pkg.Hashes[f.Name] = hash
+		if prev != nil && prev.Hashes[f.Name] == hash && reusable(prevFiles[f.Name]) {
+			if pf := prevFiles[f.Name]; pf != nil {
+				file := *pf
+				file.Name, file.URL = f.Name, f.BrowseURL
+				pkg.Files = append(pkg.Files, &file)
+			}
 			continue
 		}
-		problems, err := linter.Lint(f.Name, f.Data)
+		problems, err := safeLint(f.Name, f.Data)
 		if err == nil && len(problems) == 0 {
 			continue
 		}
 		file := lintFile{Name: f.Name, URL: f.BrowseURL}
-		if err != nil {
-			file.Problems = []*lintProblem{{Text: err.Error()}}
+		if e, ok := err.(*linterPanic); ok {
+			log.Errorf(c, "Linter panic on %s/%s: %v\n%s", pkg.Path, f.Name, e.value, e.stack)
+			file.Problems = []*lintProblem{{Text: err.Error(), Confidence: 1}}
+		} else if err != nil {
+			file.Problems = []*lintProblem{{Text: err.Error(), ParseError: true}}
 		} else {
 			for _, p := range problems {
+				lineText, truncated := truncateLineText(p.LineText, p.Position.Column, maxLineText)
 				file.Problems = append(file.Problems, &lintProblem{
-					Line:       p.Position.Line,
-					Text:       p.Text,
-					LineText:   p.LineText,
-					Confidence: p.Confidence,
-					Link:       p.Link,
+					Line:              p.Position.Line,
+					Column:            p.Position.Column,
+					Offset:            p.Position.Offset,
+					Text:              p.Text,
+					LineText:          lineText,
+					LineTextTruncated: truncated,
+					Confidence:        p.Confidence,
+					Link:              p.Link,
+					RuleID:            ruleID(p.Text),
+					SuggestedFix:      suggestFix(p.Text),
 				})
 			}
+			addContext(&file, f.Data, &contextBudget)
+		}
+		for _, p := range file.Problems {
+			p.NormalizedText = normalizeProblemText(p.Text)
 		}
 		if len(file.Problems) > 0 {
 			pkg.Files = append(pkg.Files, &file)
 		}
 	}
+	if len(names) > 1 {
+		for name := range names {
+			pkg.Packages = append(pkg.Packages, name)
+		}
+		sort.Strings(pkg.Packages)
+	}
+}
 
-	if err := putPackage(appengine.NewContext(r), importPath, &pkg); err != nil {
-		return nil, err
+// byLineAndConfidence orders the problems in a file by line and, for
+// problems on the same line, by descending confidence, so that the most
+// certain problem on a line is shown first. Problems without a line, such as
+// parse errors, come first. Stored results keep the order of golint's output,
+// which the golint format reproduces.
+type byLineAndConfidence []*lintProblem
+
+func (s byLineAndConfidence) Len() int      { return len(s) }
+func (s byLineAndConfidence) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s byLineAndConfidence) Less(i, j int) bool {
+	if s[i].Line != s[j].Line {
+		return s[i].Line < s[j].Line
 	}
+	return s[i].Confidence > s[j].Confidence
+}
 
-	return &pkg, nil
+type filesByName []*File
+
+func (s filesByName) Len() int           { return len(s) }
+func (s filesByName) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s filesByName) Less(i, j int) bool { return s[i].Name < s[j].Name }
+
+// fileHash returns the hash of a file's contents used to detect unchanged
+// files.
+func fileHash(data []byte) string {
+	h := sha1.Sum(data)
+	return hex.EncodeToString(h[:])
+}
+
+// reusable reports whether the stored result for an unchanged file can be
+// used instead of linting the file again. Results with lint errors or linter
+// panics are not reused.
+func reusable(f *lintFile) bool {
+	if f == nil {
+		return true
+	}
+	for _, p := range f.Problems {
+		if p.Line == 0 {
+			return false
+		}
+	}
+	return true
 }
 
-func filterByConfidence(r *http.Request, pkg *lintPackage) {
-	minConfidence, err := strconv.ParseFloat(r.FormValue("minConfidence"), 64)
+// generatedPat matches the comment that marks generated Go source files, as
+// described in https://golang.org/s/generatedcode.
+var generatedPat = regexp.MustCompile(`(?m)^// Code generated .* DO NOT EDIT\.$`)
+
+// ignoredPat matches the build constraint excluding a file from all builds,
+// as used for programs run with go run or go generate.
+var ignoredPat = regexp.MustCompile(`(?m)^//(?: \+build|go:build) ignore\s*$`)
+
+// packageName returns the name in the package clause of a Go source file, or
+// "" if the package clause cannot be parsed.
+func packageName(src []byte) string {
+	f, err := parser.ParseFile(token.NewFileSet(), "", src, parser.PackageClauseOnly)
 	if err != nil {
-		minConfidence = 0.8
+		return ""
+	}
+	return f.Name.Name
+}
+
+// parseExcludeFiles returns the comma separated glob patterns of the
+// excludeFiles parameter.
+func parseExcludeFiles(r *http.Request) ([]string, error) {
+	var patterns []string
+	for _, p := range strings.Split(r.FormValue("excludeFiles"), ",") {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		if _, err := filepath.Match(p, ""); err != nil {
+			return nil, &appError{Status: 400, Message: "Bad excludeFiles pattern.", Detail: p}
+		}
+		patterns = append(patterns, p)
+	}
+	return patterns, nil
+}
+
+// excludeFiles removes the files with a name or base name matching one of
+// the patterns.
+func excludeFiles(pkg *lintPackage, patterns []string) {
+	if len(patterns) == 0 {
+		return
+	}
+	j := 0
+	for _, f := range pkg.Files {
+		if !matchAny(patterns, f.Name) && !matchAny(patterns, path.Base(f.Name)) {
+			pkg.Files[j] = f
+			j++
+		}
+	}
+	pkg.Files = pkg.Files[:j]
+}
+
+// filterByFile removes the files in pkg other than the file with the given
+// base name. It returns false if the file is not a Go file of the package.
+func filterByFile(pkg *lintPackage, name string) bool {
+	found := pkg.Hashes[name] != ""
+	for _, names := range [][]string{pkg.Generated, pkg.Unfetched} {
+		for _, n := range names {
+			if path.Base(n) == name {
+				found = true
+			}
+		}
+	}
+	j := 0
+	for _, f := range pkg.Files {
+		if path.Base(f.Name) == name {
+			pkg.Files[j] = f
+			j++
+			found = true
+		}
+	}
+	pkg.Files = pkg.Files[:j]
+	return found
+}
+
+func matchAny(patterns []string, name string) bool {
+	for _, p := range patterns {
+		if ok, _ := filepath.Match(p, name); ok {
+			return true
+		}
 	}
+	return false
+}
+
+// filterSummary describes the filters applied to the problems in pkg, for
+// the X-Lint-Filter response header.
+func filterSummary(r *http.Request, pkg *lintPackage, selected *rule, file string, exclude []string) string {
+	s := fmt.Sprintf("minConfidence=%g; maxConfidence=%g", minConfidence(r, pkg.Path), maxConfidence(r))
+	if selected != nil {
+		s += "; rule=" + selected.ID
+	}
+	if r.FormValue("api") == "1" {
+		s += "; api=1"
+	}
+	if file != "" {
+		s += "; file=" + file
+	}
+	if len(exclude) > 0 {
+		s += "; excludeFiles=" + strings.Join(exclude, ",")
+	}
+	return s
+}
+
+// filterByRule removes the problems not classified as the rule with the
+// given ID.
+func filterByRule(pkg *lintPackage, id string) {
 	for _, f := range pkg.Files {
 		j := 0
 		for i := range f.Problems {
-			if f.Problems[i].Confidence >= minConfidence {
+			if f.Problems[i].RuleID == id {
 				f.Problems[j] = f.Problems[i]
 				j++
 			}
@@ -249,59 +1165,374 @@ type handlerFunc func(http.ResponseWriter, *http.Request) error
 
 func (f handlerFunc) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	c := appengine.NewContext(r)
-	err := f(w, r)
+	start := time.Now()
+	setDeployedVersion(c)
+	handled, err := checkAuth(c, w, r)
+	if handled {
+		return
+	}
+	if err == nil {
+		err = f(w, r)
+	}
 	if err == nil {
 		return
-	} else if gosrc.IsNotFound(err) {
-		writeErrorResponse(w, 404)
-	} else if e, ok := err.(*gosrc.RemoteError); ok {
-		log.Infof(c, "Remote error %s: %v", e.Host, e)
-		writeResponse(w, 500, errorTemplate, fmt.Sprintf("Error accessing %s.", e.Host))
-	} else if err != nil {
-		log.Errorf(c, "Internal error %v", err)
-		writeErrorResponse(w, 500)
+	}
+	var e *appError
+	switch err := err.(type) {
+	case *appError:
+		e = err
+	case gosrc.NotFoundError:
+		e = &appError{Status: 404, Detail: err.Message}
+	case noPackageError:
+		e = &appError{
+			Status:  404,
+			Message: "This path has no Go package.",
+			Detail:  "The directory " + err.importPath + " was found but has no .go files. If you entered a repository, add the path of a package in it.",
+		}
+	case *redirectError:
+		msg := "Too many redirects."
+		if err.loop {
+			msg = "Redirect loop detected."
+		}
+		e = &appError{Status: 502, Message: msg, Detail: strings.Join(err.paths, " -> ")}
+	case *gosrc.RemoteError:
+		log.Infof(c, "Remote error %s: %v", err.Host, err)
+		e = &appError{Status: 500, Message: fmt.Sprintf("Error accessing %s.", err.Host)}
+	default:
+		if err == errBusy {
+			log.Warningf(c, "Lint run rejected: %v", err)
+			w.Header().Set("Retry-After", "10")
+			e = &appError{Status: 503, Message: "The server is busy. Try again in a few seconds."}
+		} else {
+			log.Errorf(c, "Internal error %v", err)
+			e = &appError{Status: 500}
+		}
+	}
+	if err := writeErrorResponse(w, r, e); err != nil {
+		log.Errorf(c, "Rendering error page: %v", err)
+		http.Error(w, http.StatusText(e.Status), e.Status)
+	}
+	// Task queue requests are retried on error. Recording an event for each
+	// retry of the events task would queue another task every batch.
+	if r.Header.Get("X-AppEngine-QueueName") == "" {
+		recordEvent(c, start, &Event{Name: "error", Path: r.URL.Path, Outcome: strconv.Itoa(e.Status)})
 	}
 }
 
 func serveRoot(w http.ResponseWriter, r *http.Request) error {
 	switch {
 	case r.Method != "GET" && r.Method != "HEAD":
-		return writeErrorResponse(w, 405)
+		return &appError{Status: 405}
+	case r.URL.Path == "/" && r.FormValue("local") != "":
+		return serveLocal(w, r)
+	case r.URL.Path == "/" && homeRedirect != "":
+		http.Redirect(w, r, homeRedirect, http.StatusFound)
+		return nil
 	case r.URL.Path == "/":
-		return writeResponse(w, 200, homeTemplate, nil)
+		setCacheControl(w, homeMaxAge)
+		return writeResponse(w, r, 200, homeTemplate, nil)
+	case r.FormValue("pr") != "":
+		return errPullRequestUnsupported
 	default:
 		importPath := r.URL.Path[1:]
-		if !gosrc.IsValidPath(importPath) {
+		if p := normalizeImportPath(importPath); p != importPath {
+			if err := checkPathLimits(p); err != nil {
+				return err
+			}
+			u := url.URL{Path: "/" + p, RawQuery: r.URL.RawQuery}
+			http.Redirect(w, r, u.String(), http.StatusMovedPermanently)
+			return nil
+		}
+		var selected *rule
+		if i := strings.LastIndex(importPath, "/rule/"); i >= 0 {
+			selected = rulesByID[importPath[i+len("/rule/"):]]
+			if selected == nil {
+				return &appError{Status: 404, Message: "Unknown rule."}
+			}
+			importPath = importPath[:i]
+		}
+		var snapshot int64
+		if i := strings.LastIndex(importPath, "@"); i >= 0 {
+			t, err := strconv.ParseInt(importPath[i+1:], 10, 64)
+			if err != nil {
+				return &appError{Status: 404, Message: "Bad permalink."}
+			}
+			importPath, snapshot = importPath[:i], t
+		}
+		var file string
+		if strings.HasSuffix(importPath, ".go") && fetcher.ValidPath(path.Dir(importPath)) {
+			importPath, file = path.Dir(importPath), path.Base(importPath)
+		}
+		if err := checkPathLimits(importPath); err != nil {
+			return err
+		}
+		if !fetcher.ValidPath(importPath) {
 			return gosrc.NotFoundError{Message: "bad path"}
 		}
+		opts, err := parseLintOptions(r)
+		if err != nil {
+			return err
+		}
+		exclude, err := parseExcludeFiles(r)
+		if err != nil {
+			return err
+		}
 		c := appengine.NewContext(r)
-		pkg, err := getPackage(c, importPath)
+		start := time.Now()
+		view := &packageView{Location: requestLocation(r)}
+		var pkg *lintPackage
+		if snapshot != 0 {
+			pkg, err = store.GetSnapshot(c, opts.key(importPath), snapshot)
+			if err != nil {
+				return err
+			}
+			view.Permalink = pkg != nil
+			view.SnapshotExpired = pkg == nil
+		}
+		maxAge := homeMaxAge
+		fromCache := true
+		fresh := pkg == nil && noCache(r)
+		if fresh && !allowNoCache(c, r) {
+			w.Header().Set("Retry-After", strconv.Itoa(int(noCacheWindow.Seconds())))
+			return errNoCacheLimit
+		}
+		if pkg == nil && !fresh {
+			pkg, err = getPackage(c, opts.key(importPath))
+			if pkg != nil {
+				maxAge = cachedMaxAge(pkg.Updated)
+			}
+			if pkg != nil && needsRevalidation(pkg) {
+				// Serve the stored result without waiting for the refresh.
+				maxAge = freshMaxAge
+				w.Header().Set("X-Lint-Revalidating", "1")
+				if !isRefreshPending(c, opts.key(importPath)) {
+					if err := queueRefresh(c, importPath, opts, nil); err != nil {
+						log.Errorf(c, "Queueing refresh of %s: %v", importPath, err)
+					}
+				}
+			}
+		}
 		if pkg == nil && err == nil {
-			pkg, err = runLint(r, importPath)
+			pkg, err = runLint(r, importPath, opts)
+			maxAge = freshMaxAge
+			fromCache = false
 		}
 		if err != nil {
 			return err
 		}
-		filterByConfidence(r, pkg)
-		return writeResponse(w, 200, packageTemplate, pkg)
+		recordEvent(c, start, &Event{Name: "view", Path: pkg.Path, Outcome: "ok", FromCache: fromCache})
+		if pkg.Path != importPath {
+			// The package was just linted if nocache was set.
+			q := r.URL.Query()
+			q.Del("nocache")
+			u := url.URL{Path: "/" + path.Join(pkg.Path, file), RawQuery: q.Encode()}
+			http.Redirect(w, r, u.String(), http.StatusFound)
+			return nil
+		}
+		if fresh {
+			w.Header().Set("Cache-Control", "no-store")
+		} else {
+			setCacheControl(w, maxAge)
+		}
+		if r.FormValue("collapse") == "1" && negotiateFormat(r) == "html" {
+			markBelowThreshold(r, pkg)
+		} else {
+			filterByConfidence(r, pkg)
+		}
+		api := r.FormValue("api") == "1"
+		if snapshot == 0 && selected == nil && !api && file == "" && len(exclude) == 0 && negotiateFormat(r) == "html" {
+			view.Cleaned = getCleanedFiles(c, r, opts.key(pkg.Path), pkg)
+		}
+		if selected != nil {
+			filterByRule(pkg, selected.ID)
+			view.Rule = selected
+		}
+		if api {
+			filterAPI(pkg)
+			view.API = true
+		}
+		if file != "" {
+			if !filterByFile(pkg, file) {
+				return &appError{Status: 404, Message: "File not found in package."}
+			}
+			view.File = file
+		}
+		excludeFiles(pkg, exclude)
+		view.ExcludeFiles = exclude
+		w.Header().Set("X-Lint-Filter", filterSummary(r, pkg, selected, file, exclude))
+		trimContext(pkg, contextParam(r))
+		if r.FormValue("fullPath") == "1" {
+			setFullNames(pkg)
+		}
+		if r.FormValue("sort") == "count" {
+			sort.Stable(byProblemCount(pkg.Files))
+		}
+		view.lintPackage = pkg
+		view.Flat = r.FormValue("flat") == "1"
+		view.SortByConfidence = r.FormValue("sort") == "confidence"
+		view.RefreshPending = snapshot == 0 && isRefreshPending(c, opts.key(importPath))
+		view.ShareURL = shareURL(r)
+		view.CollapseFiles = collapseFileCount > 0 && len(pkg.Files) > collapseFileCount && r.FormValue("expand") != "all"
+		if negotiateFormat(r) == "html" {
+			if view.Note, err = getNote(c, pkg.Path); err != nil {
+				log.Warningf(c, "Getting note for %s: %v", pkg.Path, err)
+			}
+			if view.Similar, err = getSimilarPackages(c, pkg.Path); err != nil {
+				log.Warningf(c, "Listing packages similar to %s: %v", pkg.Path, err)
+			}
+		}
+		if r.FormValue("view") == "source" {
+			return serveSource(w, r, view, r.FormValue("file"))
+		}
+		w.Header().Add("Vary", "Accept")
+		return negotiate(r)(w, r, view)
 	}
 }
 
-func serveRefresh(w http.ResponseWriter, r *http.Request) error {
-	if r.Method != "POST" {
-		return writeErrorResponse(w, 405)
+// shareURL returns the absolute URL of the request r.
+func shareURL(r *http.Request) string {
+	u := url.URL{Scheme: "http", Host: r.Host, Path: r.URL.Path, RawQuery: r.URL.RawQuery}
+	if r.TLS != nil {
+		u.Scheme = "https"
 	}
-	importPath := r.FormValue("importPath")
-	pkg, err := runLint(r, importPath)
-	if err != nil {
-		return err
+	return u.String()
+}
+
+// checkPathLimits returns an error if importPath is longer than
+// maxPathLength bytes or has more than maxPathSegments elements. Such paths
+// are rejected before any storage lookup or fetch.
+func checkPathLimits(importPath string) error {
+	if len(importPath) > maxPathLength {
+		return &appError{Status: 400, Message: "Import path too long."}
+	}
+	if strings.Count(importPath, "/")+1 > maxPathSegments {
+		return &appError{Status: 400, Message: "Import path has too many elements."}
 	}
-	http.Redirect(w, r, "/"+pkg.Path, 301)
 	return nil
 }
 
+const (
+	// freshMaxAge is the max-age of a package linted by the request.
+	freshMaxAge = time.Minute
+
+	// homeMaxAge is the max-age of the home page and of snapshots, which do
+	// not change.
+	homeMaxAge = 24 * time.Hour
+)
+
+// cachedMaxAge returns the max-age of a stored package updated at the given
+// time: the remainder of packageTTL, but at least freshMaxAge.
+func cachedMaxAge(updated time.Time) time.Duration {
+	d := packageTTL - now().Sub(updated)
+	if d < freshMaxAge {
+		d = freshMaxAge
+	}
+	return d
+}
+
+// needsRevalidation reports whether a stored package is served while it is
+// refreshed in the background, because it was stored with an older version
+// or was updated more than packageTTL ago.
+func needsRevalidation(pkg *lintPackage) bool {
+	return pkg.stale || packageTTL > 0 && now().Sub(pkg.Updated) > packageTTL
+}
+
+// noCache reports whether the request asks for a package to be linted
+// again instead of served from the stored result with nocache=1. A
+// Cache-Control: no-cache header is not enough, since browsers send it on
+// every hard reload.
+func noCache(r *http.Request) bool {
+	return r.FormValue("nocache") == "1"
+}
+
+// noCacheWindow is the period over which maxNoCacheRuns is counted.
+const noCacheWindow = time.Minute
+
+var errNoCacheLimit = &appError{
+	Status:  429,
+	Message: "Too many nocache requests.",
+	Detail:  "Wait a minute before asking for a package to be linted again, or leave out nocache=1 to see the stored result.",
+}
+
+// allowNoCache reports whether the client that sent r may run another
+// nocache lint. Clients are counted by address in memcache, so the limit
+// holds across instances. If memcache fails the request is allowed.
+func allowNoCache(c context.Context, r *http.Request) bool {
+	if maxNoCacheRuns <= 0 {
+		return true
+	}
+	key := "nocache:" + clientAddr(r)
+	n, err := memcache.IncrementExisting(c, key, 1)
+	if err == memcache.ErrCacheMiss {
+		n = 1
+		err = memcache.Add(c, &memcache.Item{Key: key, Value: []byte("1"), Expiration: noCacheWindow})
+		if err == memcache.ErrNotStored {
+			// Another request started the window first.
+			n, err = memcache.IncrementExisting(c, key, 1)
+		}
+	}
+	if err != nil {
+		log.Warningf(c, "Counting nocache runs for %s: %v", key, err)
+		return true
+	}
+	return n <= uint64(maxNoCacheRuns)
+}
+
+// clientAddr returns the address of the client that sent r, without the
+// port.
+func clientAddr(r *http.Request) string {
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
+
+// setCacheControl lets shared caches keep the response for maxAge, unless
+// the site is private.
+func setCacheControl(w http.ResponseWriter, maxAge time.Duration) {
+	if authEnabled() {
+		w.Header().Set("Cache-Control", "private, no-store")
+		return
+	}
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", maxAge/time.Second))
+}
+
+// requestLocation returns the time zone named by the tz request parameter,
+// or nil if the parameter is missing or not a known zone.
+func requestLocation(r *http.Request) *time.Location {
+	tz := r.FormValue("tz")
+	if tz == "" {
+		return nil
+	}
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return nil
+	}
+	return loc
+}
+
 func serveBot(w http.ResponseWriter, r *http.Request) error {
 	c := appengine.NewContext(r)
-	_, err := fmt.Fprintf(w, "Contact %s for help with the %s bot.", contactEmail, appengine.AppID(c))
-	return err
+	bot := struct {
+		Bot     string
+		Contact string
+		Abuse   string `json:",omitempty"`
+		Status  string `json:",omitempty"`
+		Source  string `json:",omitempty"`
+	}{appengine.AppID(c), contactEmail, abuseEmail, statusURL, sourceURL}
+	if r.FormValue("format") == "json" {
+		return writeJSONResponse(w, 200, &bot)
+	}
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "Contact %s for help with the %s bot.\n", bot.Contact, bot.Bot)
+	if bot.Abuse != "" {
+		fmt.Fprintf(&buf, "Report abuse to %s.\n", bot.Abuse)
+	}
+	if bot.Status != "" {
+		fmt.Fprintf(&buf, "Service status: %s\n", bot.Status)
+	}
+	if bot.Source != "" {
+		fmt.Fprintf(&buf, "Source code: %s\n", bot.Source)
+	}
+	return writeTextResponse(w, 200, buf.String())
 }
@@ -0,0 +1,489 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package lintapp
+
+import (
+	"go/token"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/lint"
+	"golang.org/x/net/context"
+	"google.golang.org/appengine"
+	"google.golang.org/appengine/aetest"
+)
+
+func TestSafeLintPanic(t *testing.T) {
+	defer func(old func(string, []byte) ([]lint.Problem, error)) { lintSource = old }(lintSource)
+	lintSource = func(filename string, src []byte) ([]lint.Problem, error) {
+		panic("boom")
+	}
+
+	problems, err := safeLint("x.go", []byte("package x\n"))
+	if problems != nil {
+		t.Errorf("got problems %v, want none", problems)
+	}
+	e, ok := err.(*linterPanic)
+	if !ok {
+		t.Fatalf("got error %v, want *linterPanic", err)
+	}
+	if e.value != "boom" {
+		t.Errorf("got panic value %v, want boom", e.value)
+	}
+	if e.Error() != "golint crashed analyzing this file" {
+		t.Errorf("unexpected error message %q", e.Error())
+	}
+}
+
+func TestSafeLint(t *testing.T) {
+	problems, err := safeLint("x.go", []byte("// Package x is x.\npackage x\n\nfunc F() {}\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(problems) != 1 || problems[0].Position.Line != 4 {
+		t.Errorf("got problems %+v, want one problem on line 4", problems)
+	}
+}
+
+func TestCheckPathLimits(t *testing.T) {
+	defer func(n, m int) { maxPathLength, maxPathSegments = n, m }(maxPathLength, maxPathSegments)
+	maxPathLength, maxPathSegments = 20, 4
+
+	tests := []struct {
+		path string
+		ok   bool
+	}{
+		{"github.com/a/b", true},
+		{"github.com/abcdefghi", true},
+		{"github.com/abcdefghij", false},
+		{"github.com/a/b/c", true},
+		{"github.com/a/b/c/d", false},
+	}
+	for _, tt := range tests {
+		err := checkPathLimits(tt.path)
+		if (err == nil) != tt.ok {
+			t.Errorf("checkPathLimits(%q) = %v, want ok %v", tt.path, err, tt.ok)
+		}
+		if e, ok := err.(*appError); err != nil && (!ok || e.Status != 400) {
+			t.Errorf("checkPathLimits(%q) = %#v, want 400 appError", tt.path, err)
+		}
+	}
+}
+
+func TestServeRootRejectsLongPath(t *testing.T) {
+	r, _ := http.NewRequest("GET", "/github.com/"+strings.Repeat("a/", maxPathSegments), nil)
+	err := serveRoot(httptest.NewRecorder(), r)
+	if e, ok := err.(*appError); !ok || e.Status != 400 {
+		t.Errorf("serveRoot returned %v, want 400 appError", err)
+	}
+}
+
+func TestServeRootRejectsPullRequest(t *testing.T) {
+	r, _ := http.NewRequest("GET", "/github.com/user/repo?pr=12", nil)
+	if err := serveRoot(httptest.NewRecorder(), r); err != errPullRequestUnsupported {
+		t.Errorf("serveRoot returned %v, want errPullRequestUnsupported", err)
+	}
+}
+
+func TestLintFilesReusesUnchanged(t *testing.T) {
+	defer func(old func(string, []byte) ([]lint.Problem, error)) { lintSource = old }(lintSource)
+	var linted []string
+	lintSource = func(filename string, src []byte) ([]lint.Problem, error) {
+		linted = append(linted, filename)
+		return []lint.Problem{{Position: token.Position{Line: 1}, Text: "problem in " + filename, Confidence: 1}}, nil
+	}
+
+	files := []*File{
+		{Name: "a.go", Data: []byte("package a\n")},
+		{Name: "b.go", Data: []byte("package a\n\nvar b int\n")},
+	}
+	prev := &lintPackage{}
+	lintFiles(context.Background(), prev, files, nil)
+	setRepoRelativeNames(prev, "sub")
+
+	files[1] = &File{Name: "b.go", Data: []byte("package a\n\nvar c int\n")}
+	linted = nil
+	pkg := &lintPackage{}
+	lintFiles(context.Background(), pkg, files, prev)
+	if len(linted) != 1 || linted[0] != "b.go" {
+		t.Errorf("linted %v, want [b.go]", linted)
+	}
+	if len(pkg.Files) != 2 || pkg.Files[0].Name != "a.go" || pkg.Files[0].Problems[0].Text != "problem in a.go" {
+		t.Errorf("result for unchanged file not reused: %+v", pkg.Files)
+	}
+
+	linted = nil
+	lintFiles(context.Background(), &lintPackage{}, files, compactPackage(pkg))
+	if len(linted) != 2 {
+		t.Errorf("linted %v with a compact previous result, want all files", linted)
+	}
+}
+
+func TestExcludeFiles(t *testing.T) {
+	r, _ := http.NewRequest("GET", "/github.com/a/b?excludeFiles=*_gen.go,+testdata/*", nil)
+	patterns, err := parseExcludeFiles(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkg := &lintPackage{Files: []*lintFile{
+		{Name: "a.go"},
+		{Name: "sub/x_gen.go"},
+		{Name: "testdata/t.go"},
+		{Name: "sub/testdata/t.go"},
+	}}
+	excludeFiles(pkg, patterns)
+	var names []string
+	for _, f := range pkg.Files {
+		names = append(names, f.Name)
+	}
+	if len(names) != 2 || names[0] != "a.go" || names[1] != "sub/testdata/t.go" {
+		t.Errorf("kept %v, want [a.go sub/testdata/t.go]", names)
+	}
+
+	r, _ = http.NewRequest("GET", "/github.com/a/b?excludeFiles=[", nil)
+	if _, err := parseExcludeFiles(r); err == nil {
+		t.Error("parseExcludeFiles accepted a bad pattern")
+	}
+}
+
+func TestProblemCount(t *testing.T) {
+	f := &lintFile{Problems: []*lintProblem{
+		{Text: "a", BelowThreshold: true},
+		{Text: "b"},
+		{Text: "c"},
+	}}
+	if n := f.ProblemCount(); n != 2 {
+		t.Errorf("ProblemCount() = %d, want 2", n)
+	}
+}
+
+func TestFilterByFile(t *testing.T) {
+	newPackage := func() *lintPackage {
+		return &lintPackage{
+			Files: []*lintFile{
+				{Name: "sub/a.go"},
+				{Name: "sub/b.go"},
+			},
+			Generated: []string{"sub/gen.go"},
+			Hashes:    map[string]string{"a.go": "1", "b.go": "2", "c.go": "3"},
+		}
+	}
+	for _, tt := range []struct {
+		name  string
+		found bool
+		files int
+	}{
+		{"a.go", true, 1},
+		{"c.go", true, 0},
+		{"gen.go", true, 0},
+		{"d.go", false, 0},
+	} {
+		pkg := newPackage()
+		found := filterByFile(pkg, tt.name)
+		if found != tt.found || len(pkg.Files) != tt.files {
+			t.Errorf("filterByFile(%q) = %v with %d files, want %v with %d files", tt.name, found, len(pkg.Files), tt.found, tt.files)
+		}
+	}
+}
+
+func TestLintFilesLimit(t *testing.T) {
+	defer func(old int) { maxFilesPerPackage = old }(maxFilesPerPackage)
+	maxFilesPerPackage = 2
+
+	files := []*File{
+		{Name: "c.go", Data: []byte("package a\n\nvar c_c int\n")},
+		{Name: "a.go", Data: []byte("package a\n\nvar a_a int\n")},
+		{Name: "b.go", Data: []byte("package a\n\nvar b_b int\n")},
+	}
+	pkg := &lintPackage{}
+	lintFiles(context.Background(), pkg, files, nil)
+	if pkg.Unlinted != 1 || len(pkg.Files) != 2 || pkg.Files[0].Name != "a.go" || pkg.Files[1].Name != "b.go" {
+		t.Errorf("linted %+v with %d unlinted, want a.go and b.go with 1 unlinted", pkg.Files, pkg.Unlinted)
+	}
+
+	pkg = &lintPackage{Options: lintOptions{All: true}}
+	lintFiles(context.Background(), pkg, files, nil)
+	if pkg.Unlinted != 0 || len(pkg.Files) != 3 {
+		t.Errorf("linted %d files with %d unlinted and all=1, want 3 with 0 unlinted", len(pkg.Files), pkg.Unlinted)
+	}
+}
+
+func TestLintFilesPackages(t *testing.T) {
+	tests := []struct {
+		files []*File
+		want  []string
+	}{
+		{
+			[]*File{
+				{Name: "a.go", Data: []byte("package foo\n")},
+				{Name: "a_test.go", Data: []byte("package foo_test\n")},
+				{Name: "gen.go", Data: []byte("// +build ignore\n\npackage main\n")},
+				{Name: "tool.go", Data: []byte("//go:build ignore\n\npackage main\n")},
+			},
+			nil,
+		},
+		{
+			[]*File{
+				{Name: "a.go", Data: []byte("package foo\n")},
+				{Name: "b.go", Data: []byte("package bar\n")},
+				{Name: "b_test.go", Data: []byte("package bar_test\n")},
+			},
+			[]string{"bar", "foo"},
+		},
+	}
+	for i, tt := range tests {
+		pkg := &lintPackage{}
+		lintFiles(context.Background(), pkg, tt.files, nil)
+		if !reflect.DeepEqual(pkg.Packages, tt.want) {
+			t.Errorf("%d: Packages = %v, want %v", i, pkg.Packages, tt.want)
+		}
+	}
+}
+
+func TestLinterChanged(t *testing.T) {
+	for _, tt := range []struct {
+		prev *lintPackage
+		want bool
+	}{
+		{nil, false},
+		{&lintPackage{LinterVersion: linterVersion}, false},
+		{&lintPackage{LinterVersion: "old"}, true},
+		{&lintPackage{}, true},
+		{&lintPackage{LinterVersion: linterVersion, stale: true}, true},
+	} {
+		if got := linterChanged(tt.prev); got != tt.want {
+			t.Errorf("linterChanged(%+v) = %v, want %v", tt.prev, got, tt.want)
+		}
+	}
+}
+
+func TestPutPackageLinterChanged(t *testing.T) {
+	defer func(oldStore Store, oldCache *packageCache) { store, hotPackages = oldStore, oldCache }(store, hotPackages)
+	store, hotPackages = newMemoryStore(), newPackageCache(10, time.Minute)
+	c := context.Background()
+	pkg := &lintPackage{Path: "github.com/user/repo", LinterVersion: linterVersion, LinterChanged: true}
+	if err := putPackage(c, "github.com/user/repo", pkg); err != nil {
+		t.Fatal(err)
+	}
+	if !pkg.LinterChanged {
+		t.Error("putPackage cleared LinterChanged of the lint run's result")
+	}
+	if got := hotPackages.get("github.com/user/repo"); got == nil || got.LinterChanged {
+		t.Errorf("cached package = %+v, want LinterChanged false", got)
+	}
+	if got, _ := store.Get(c, "github.com/user/repo"); got == nil || got.LinterChanged {
+		t.Errorf("stored package = %+v, want LinterChanged false", got)
+	}
+}
+
+func TestWithinVersionGrace(t *testing.T) {
+	defer func(old func() time.Time) { now = old }(now)
+	defer func(old time.Duration) { versionGrace = old }(versionGrace)
+	t0 := time.Date(2016, 5, 1, 12, 0, 0, 0, time.UTC)
+	now = func() time.Time { return t0 }
+	tests := []struct {
+		grace   time.Duration
+		updated time.Time
+		want    bool
+	}{
+		{0, t0, false},
+		{time.Hour, t0.Add(-time.Minute), true},
+		{time.Hour, t0.Add(-time.Hour), true},
+		{time.Hour, t0.Add(-2 * time.Hour), false},
+	}
+	for _, tt := range tests {
+		versionGrace = tt.grace
+		if got := withinVersionGrace(&lintPackage{Updated: tt.updated, stale: true}); got != tt.want {
+			t.Errorf("withinVersionGrace(updated %v) with grace %v = %v, want %v", tt.updated, tt.grace, got, tt.want)
+		}
+	}
+}
+
+func TestFlatProblems(t *testing.T) {
+	pkg := &lintPackage{Files: []*lintFile{
+		{Name: "a.go", Problems: []*lintProblem{{Text: "a1", Confidence: 0.5}, {Text: "a2", Confidence: 1}}},
+		{Name: "b.go", Problems: []*lintProblem{{Text: "b1", Confidence: 0.9}, {Text: "b2", Confidence: 0.2, BelowThreshold: true}}},
+	}}
+	for _, tt := range []struct {
+		sortByConfidence bool
+		want             string
+	}{
+		{false, "a.go:a1 a.go:a2 b.go:b1"},
+		{true, "a.go:a2 b.go:b1 a.go:a1"},
+	} {
+		v := &packageView{lintPackage: pkg, Flat: true, SortByConfidence: tt.sortByConfidence}
+		var got []string
+		for _, item := range v.FlatProblems() {
+			got = append(got, item.File.Name+":"+item.Text)
+		}
+		if s := strings.Join(got, " "); s != tt.want {
+			t.Errorf("FlatProblems with SortByConfidence=%v = %s, want %s", tt.sortByConfidence, s, tt.want)
+		}
+	}
+}
+
+func TestWriteResponseExecuteError(t *testing.T) {
+	tmpl := template.Must(template.New("ROOT").Parse(`<p>{{index . 5}}`))
+	w := httptest.NewRecorder()
+	r, _ := http.NewRequest("GET", "/", nil)
+	if err := writeResponse(w, r, 200, tmpl, []int{}); err == nil {
+		t.Fatal("writeResponse returned no error for a failing template")
+	}
+	if w.Body.Len() != 0 {
+		t.Errorf("writeResponse wrote %q for a failing template, want nothing", w.Body.String())
+	}
+}
+
+func TestByLineAndConfidence(t *testing.T) {
+	problems := []*lintProblem{
+		{Line: 3, Column: 1, Text: "low", Confidence: 0.3},
+		{Line: 3, Column: 6, Text: "high", Confidence: 0.9},
+		{Line: 3, Column: 8, Text: "mid", Confidence: 0.6},
+		{Line: 5, Column: 1, Text: "next", Confidence: 1},
+		{Text: "parse error"},
+	}
+	sort.Stable(byLineAndConfidence(problems))
+	var got []string
+	for _, p := range problems {
+		got = append(got, p.Text)
+	}
+	if s := strings.Join(got, ", "); s != "parse error, high, mid, low, next" {
+		t.Errorf("problems ordered %s, want parse error, high, mid, low, next", s)
+	}
+}
+
+func TestTruncateLineText(t *testing.T) {
+	tests := []struct {
+		s         string
+		col, n    int
+		want      string
+		truncated bool
+	}{
+		{"abcdef", 3, 6, "abcdef", false},
+		{"abcdef", 3, 0, "abcdef", false},
+		{"abcdefghij", 0, 5, "abcd…", true},
+		{"abcdefghij", 1, 5, "abcd…", true},
+		{"abcdefghij", 6, 5, "…efg…", true},
+		{"abcdefghij", 10, 5, "…ghij", true},
+		{"abcdefghij", 6, 1, "…", true},
+		{"abcdefghij", 6, 2, "a…", true},
+		// Columns are byte offsets; each of these letters is two bytes.
+		{"αβγδεζηθικ", 9, 5, "…δεζ…", true},
+		{"αβγδεζηθικ", 10, 5, "…δεζ…", true},
+		{"αβγδεζηθικ", 19, 5, "…ηθικ", true},
+	}
+	for _, tt := range tests {
+		got, truncated := truncateLineText(tt.s, tt.col, tt.n)
+		if got != tt.want || truncated != tt.truncated {
+			t.Errorf("truncateLineText(%q, %d, %d) = %q, %v, want %q, %v", tt.s, tt.col, tt.n, got, truncated, tt.want, tt.truncated)
+		}
+	}
+}
+
+func TestNoCache(t *testing.T) {
+	tests := []struct {
+		url, cacheControl string
+		want              bool
+	}{
+		{"/github.com/user/repo", "", false},
+		{"/github.com/user/repo?nocache=1", "", true},
+		{"/github.com/user/repo?nocache=0", "", false},
+		{"/github.com/user/repo", "no-cache", false},
+		{"/github.com/user/repo", "max-age=0, No-Cache", false},
+		{"/github.com/user/repo", "max-age=0", false},
+	}
+	for _, tt := range tests {
+		r, _ := http.NewRequest("GET", tt.url, nil)
+		if tt.cacheControl != "" {
+			r.Header.Set("Cache-Control", tt.cacheControl)
+		}
+		if got := noCache(r); got != tt.want {
+			t.Errorf("noCache(%s, Cache-Control %q) = %v, want %v", tt.url, tt.cacheControl, got, tt.want)
+		}
+	}
+}
+
+func TestAllowNoCache(t *testing.T) {
+	inst, err := aetest.NewInstance(nil)
+	if err != nil {
+		t.Skipf("App Engine development server not available: %v", err)
+	}
+	defer inst.Close()
+	defer func(old int) { maxNoCacheRuns = old }(maxNoCacheRuns)
+	maxNoCacheRuns = 2
+	request := func(addr string) *http.Request {
+		r, err := inst.NewRequest("GET", "/github.com/user/repo?nocache=1", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.RemoteAddr = addr
+		return r
+	}
+	for i, want := range []bool{true, true, false} {
+		r := request("192.0.2.1:1234")
+		if got := allowNoCache(appengine.NewContext(r), r); got != want {
+			t.Errorf("request %d: allowNoCache = %v, want %v", i, got, want)
+		}
+	}
+	r := request("192.0.2.2:1234")
+	if !allowNoCache(appengine.NewContext(r), r) {
+		t.Error("allowNoCache for another client = false, want true")
+	}
+}
+
+func TestNeedsRevalidation(t *testing.T) {
+	defer func(old func() time.Time) { now = old }(now)
+	defer func(old time.Duration) { packageTTL = old }(packageTTL)
+	t0 := time.Date(2016, 5, 1, 12, 0, 0, 0, time.UTC)
+	now = func() time.Time { return t0 }
+	packageTTL = 24 * time.Hour
+	tests := []struct {
+		pkg  *lintPackage
+		want bool
+	}{
+		{&lintPackage{Updated: t0.Add(-time.Hour)}, false},
+		{&lintPackage{Updated: t0.Add(-25 * time.Hour)}, true},
+		{&lintPackage{Updated: t0.Add(-time.Hour), stale: true}, true},
+	}
+	for i, tt := range tests {
+		if got := needsRevalidation(tt.pkg); got != tt.want {
+			t.Errorf("%d: needsRevalidation = %v, want %v", i, got, tt.want)
+		}
+	}
+	packageTTL = 0
+	if needsRevalidation(&lintPackage{Updated: t0.Add(-25 * time.Hour)}) {
+		t.Error("needsRevalidation with packageTTL 0 = true, want false")
+	}
+}
+
+func TestCachedMaxAge(t *testing.T) {
+	defer func(old func() time.Time) { now = old }(now)
+	defer func(old time.Duration) { packageTTL = old }(packageTTL)
+	t0 := time.Date(2016, 5, 1, 12, 0, 0, 0, time.UTC)
+	now = func() time.Time { return t0 }
+	packageTTL = 24 * time.Hour
+	tests := []struct {
+		updated time.Time
+		want    time.Duration
+	}{
+		{t0, 24 * time.Hour},
+		{t0.Add(-time.Hour), 23 * time.Hour},
+		{t0.Add(-24 * time.Hour), freshMaxAge},
+		{t0.Add(-48 * time.Hour), freshMaxAge},
+	}
+	for _, tt := range tests {
+		if got := cachedMaxAge(tt.updated); got != tt.want {
+			t.Errorf("cachedMaxAge(%v) = %v, want %v", tt.updated, got, tt.want)
+		}
+	}
+}
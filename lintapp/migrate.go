@@ -0,0 +1,162 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package lintapp
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"golang.org/x/net/context"
+	"google.golang.org/appengine"
+	"google.golang.org/appengine/datastore"
+	"google.golang.org/appengine/log"
+)
+
+// migrations maps a stored version to the function updating a package
+// decoded from that version to the next version. gob decodes data stored
+// before fields were added, so a version that only added fields can be
+// migrated without linting the package again. Packages stored with a version
+// that has no migration are linted again when they are requested.
+var migrations = map[int]func(*lintPackage){
+	// Versions 16 and 17 added the suggested fixes and normalized texts of
+	// the problems, and version 18 the package names of directories with
+	// several packages. The package names are only found by linting, so
+	// older packages have no migration.
+
+	// Version 19 marked parse errors, the only problems stored without a
+	// line or a confidence.
+	18: func(pkg *lintPackage) {
+		for _, f := range pkg.Files {
+			for _, p := range f.Problems {
+				p.ParseError = p.Line == 0 && p.Confidence == 0
+			}
+		}
+	},
+}
+
+// errNoMigration is returned by migratePackage for a version that cannot be
+// migrated to the current version.
+var errNoMigration = errors.New("no migration to the current version")
+
+// migratePackage decodes a package stored with an older version, applies
+// the migrations to the current version and encodes it again.
+func migratePackage(spkg *storePackage) (*storePackage, error) {
+	if spkg.Version >= version {
+		return nil, errNoMigration
+	}
+	for v := spkg.Version; v < version; v++ {
+		if migrations[v] == nil {
+			return nil, errNoMigration
+		}
+	}
+	var pkg lintPackage
+	if err := gob.NewDecoder(bytes.NewReader(spkg.Data)).Decode(&pkg); err != nil {
+		return nil, err
+	}
+	for v := spkg.Version; v < version; v++ {
+		migrations[v](&pkg)
+	}
+	data, err := encodePackage(&pkg)
+	if err != nil {
+		return nil, err
+	}
+	migrated := &storePackage{Data: data, Version: version, Path: spkg.Path}
+	if migrated.Path != "" {
+		migrated.Problems = defaultProblemCount(&pkg)
+	}
+	return migrated, nil
+}
+
+// migrateResult is the response of the migrate endpoint.
+type migrateResult struct {
+	Migrated int
+
+	// Skipped counts the packages stored with a version that has no
+	// migration, or updated by a lint run during the migration.
+	Skipped int
+
+	Failed int
+
+	// Cursor continues the migration if there are more packages to scan.
+	Cursor string `json:",omitempty"`
+}
+
+// maxMigrateBatch is the maximum number of packages scanned by one request
+// to the migrate endpoint.
+const maxMigrateBatch = 500
+
+// serveAdminMigrate migrates up to limit packages stored with an older
+// version to the current version, starting at the cursor parameter, and
+// responds with the counts and the cursor of the next batch.
+func serveAdminMigrate(w http.ResponseWriter, r *http.Request) error {
+	if r.Method != "POST" {
+		return &appError{Status: 405}
+	}
+	limit := 100
+	if s := r.FormValue("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n <= 0 || n > maxMigrateBatch {
+			return &appError{Status: 400, Message: "Bad limit parameter."}
+		}
+		limit = n
+	}
+	c := appengine.NewContext(r)
+	q := datastore.NewQuery("Package").Filter("Version <", version).KeysOnly()
+	if s := r.FormValue("cursor"); s != "" {
+		cursor, err := datastore.DecodeCursor(s)
+		if err != nil {
+			return &appError{Status: 400, Message: "Bad cursor parameter."}
+		}
+		q = q.Start(cursor)
+	}
+	var result migrateResult
+	it := q.Run(c)
+	for n := 0; n < limit; n++ {
+		k, err := it.Next(nil)
+		if err == datastore.Done {
+			return writeJSONResponse(w, 200, &result)
+		}
+		if err != nil {
+			return err
+		}
+		switch err := migrateEntity(c, k); err {
+		case nil:
+			result.Migrated++
+		case errNoMigration:
+			result.Skipped++
+		default:
+			log.Warningf(c, "Migrating %s: %v", k.StringID(), err)
+			result.Failed++
+		}
+	}
+	cursor, err := it.Cursor()
+	if err != nil {
+		return err
+	}
+	result.Cursor = cursor.String()
+	return writeJSONResponse(w, 200, &result)
+}
+
+// migrateEntity migrates the Package entity with key k in a transaction, so
+// that a result stored by a concurrent lint run is not overwritten.
+func migrateEntity(c context.Context, k *datastore.Key) error {
+	return datastore.RunInTransaction(c, func(c context.Context) error {
+		var spkg storePackage
+		if err := datastore.Get(c, k, &spkg); err != nil {
+			return err
+		}
+		migrated, err := migratePackage(&spkg)
+		if err != nil {
+			return err
+		}
+		_, err = datastore.Put(c, k, migrated)
+		return err
+	}, nil)
+}
@@ -0,0 +1,50 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package lintapp
+
+import (
+	"bytes"
+	"encoding/gob"
+	"testing"
+)
+
+func TestMigratePackage(t *testing.T) {
+	pkg := &lintPackage{
+		Path: "example.com/a",
+		Files: []*lintFile{{Name: "a.go", Problems: []*lintProblem{
+			{Line: 1, Text: "var userId should be userID", Confidence: 0.9},
+			{Text: "a.go:1:1: expected 'package', found 'EOF'"},
+		}}},
+	}
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(pkg); err != nil {
+		t.Fatal(err)
+	}
+
+	spkg, err := migratePackage(&storePackage{Data: buf.Bytes(), Version: 18})
+	if err != nil {
+		t.Fatalf("migratePackage returned %v", err)
+	}
+	if spkg.Version != version {
+		t.Errorf("migrated version = %d, want %d", spkg.Version, version)
+	}
+	got, err := decodePackage(spkg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := got.Files[0].Problems; p[0].ParseError || !p[1].ParseError {
+		t.Errorf("migrated ParseError = %v, %v; want false, true", p[0].ParseError, p[1].ParseError)
+	}
+
+	// Packages stored before version 18 lack the package names, which
+	// only a lint run sets.
+	for _, v := range []int{version, 17, 15, 1} {
+		if _, err := migratePackage(&storePackage{Data: buf.Bytes(), Version: v}); err != errNoMigration {
+			t.Errorf("migratePackage of version %d returned %v, want errNoMigration", v, err)
+		}
+	}
+}
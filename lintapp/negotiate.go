@@ -0,0 +1,118 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package lintapp
+
+import (
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// renderer writes the filtered package in v in one output format.
+type renderer func(w http.ResponseWriter, r *http.Request, v *packageView) error
+
+// renderers maps format names to renderers. To add an output format, add its
+// renderer here and, if it has a media type, an entry in mediaTypeFormats.
+// The map is set in init because the renderers depend on negotiateFormat.
+var renderers map[string]renderer
+
+func init() {
+	renderers = map[string]renderer{
+		"html": func(w http.ResponseWriter, r *http.Request, v *packageView) error {
+			for _, f := range v.Files {
+				sort.Stable(byLineAndConfidence(f.Problems))
+			}
+			return writeResponse(w, r, 200, packageTemplate, v)
+		},
+		"json": func(w http.ResponseWriter, r *http.Request, v *packageView) error {
+			return writeJSONResponse(w, 200, v.lintPackage)
+		},
+		"summary": func(w http.ResponseWriter, r *http.Request, v *packageView) error {
+			return writeSummaryResponse(w, v.lintPackage, minConfidence(r, v.Path))
+		},
+		"issue": func(w http.ResponseWriter, r *http.Request, v *packageView) error {
+			return writeIssueResponse(w, r, v.lintPackage, minConfidence(r, v.Path))
+		},
+		"golint": func(w http.ResponseWriter, r *http.Request, v *packageView) error {
+			return writeGolintResponse(w, v.lintPackage)
+		},
+		"lsp": func(w http.ResponseWriter, r *http.Request, v *packageView) error {
+			return writeLSPResponse(w, v.lintPackage)
+		},
+		"checkstyle": func(w http.ResponseWriter, r *http.Request, v *packageView) error {
+			return writeCheckstyleResponse(w, v.lintPackage)
+		},
+		"gha": func(w http.ResponseWriter, r *http.Request, v *packageView) error {
+			return writeGHAResponse(w, v.lintPackage)
+		},
+		"histogram": func(w http.ResponseWriter, r *http.Request, v *packageView) error {
+			return writeJSONResponse(w, 200, ruleHistogram(v.lintPackage))
+		},
+	}
+}
+
+// formatMinConfidence maps format names to the default minimum confidence of
+// the problems in the format, for formats that do not use the default for
+// the package path. The json, lsp, checkstyle, gha and histogram formats are
+// read by tools that decide for themselves, so they include all problems. The
+// html, summary, issue and golint formats are read by people and use the path
+// default, 0.8 unless overridden. The minConfidence parameter takes
+// precedence over both.
+var formatMinConfidence = map[string]float64{
+	"json":       0,
+	"lsp":        0,
+	"checkstyle": 0,
+	"gha":        0,
+	"histogram":  0,
+}
+
+// mediaTypeFormats maps the media types accepted in the Accept header to
+// format names.
+var mediaTypeFormats = map[string]string{
+	"text/html":        "html",
+	"application/json": "json",
+	"text/plain":       "summary",
+	"text/markdown":    "issue",
+}
+
+// negotiate returns the renderer for the request.
+func negotiate(r *http.Request) renderer {
+	return renderers[negotiateFormat(r)]
+}
+
+// negotiateFormat returns the name of the format for the request. A known
+// format parameter takes precedence over the Accept header. From the Accept
+// header, the known media type with the highest quality wins, with ties
+// broken by order in the header. The default is html.
+func negotiateFormat(r *http.Request) string {
+	if format := r.FormValue("format"); renderers[format] != nil {
+		return format
+	}
+	best, bestQ := "html", 0.0
+	for _, s := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, params, err := mime.ParseMediaType(s)
+		if err != nil {
+			continue
+		}
+		format, ok := mediaTypeFormats[mediaType]
+		if !ok {
+			continue
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+		if q > bestQ {
+			best, bestQ = format, q
+		}
+	}
+	return best
+}
@@ -0,0 +1,44 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package lintapp
+
+import (
+	"net/http"
+	"testing"
+)
+
+var negotiateFormatTests = []struct {
+	query  string
+	accept string
+	want   string
+}{
+	{"", "", "html"},
+	{"", "*/*", "html"},
+	{"", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8", "html"},
+	{"", "application/json", "json"},
+	{"", "text/plain", "summary"},
+	{"", "text/markdown; charset=utf-8", "issue"},
+	{"", "text/html;q=0.5, application/json", "json"},
+	{"", "application/json;q=0.5, text/plain;q=0.5", "json"},
+	{"", "application/json;q=0, text/plain;q=bad", "html"},
+	{"format=json", "", "json"},
+	{"format=summary", "application/json", "summary"},
+	{"format=html", "application/json", "html"},
+	{"format=unknown", "application/json", "json"},
+}
+
+func TestNegotiateFormat(t *testing.T) {
+	for _, tt := range negotiateFormatTests {
+		r, _ := http.NewRequest("GET", "/github.com/a/b?"+tt.query, nil)
+		if tt.accept != "" {
+			r.Header.Set("Accept", tt.accept)
+		}
+		if got := negotiateFormat(r); got != tt.want {
+			t.Errorf("negotiateFormat(%q, Accept: %q) = %q, want %q", tt.query, tt.accept, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package lintapp
+
+import (
+	"regexp"
+	"strings"
+)
+
+// schemePat matches a URL scheme pasted in front of an import path. The
+// ServeMux cleans the request path, so https://host arrives as https:/host.
+var schemePat = regexp.MustCompile(`^(?i:https?|git|ssh):/+`)
+
+// repoHosts are the code hosting sites on which a www. prefix and a .git
+// suffix are not part of the import path. Elsewhere, a .git suffix may be a
+// VCS qualifier and www. may be the host of a custom import path.
+var repoHosts = map[string]bool{
+	"github.com":    true,
+	"bitbucket.org": true,
+	"launchpad.net": true,
+}
+
+// normalizeImportPath maps the URL forms people paste for a repository, such
+// as https://www.github.com/user/repo.git/, to the import path. The host is
+// lower-cased, since host names are not case sensitive.
+func normalizeImportPath(importPath string) string {
+	p := schemePat.ReplaceAllString(importPath, "")
+	p = strings.TrimRight(p, "/")
+	host, rest := p, ""
+	if i := strings.Index(p, "/"); i >= 0 {
+		host, rest = p[:i], p[i:]
+	}
+	host = strings.ToLower(host)
+	if h := strings.TrimPrefix(host, "www."); repoHosts[h] {
+		host = h
+		rest = strings.TrimSuffix(rest, ".git")
+	}
+	return host + rest
+}
@@ -0,0 +1,33 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package lintapp
+
+import "testing"
+
+var normalizeImportPathTests = []struct {
+	in, out string
+}{
+	{"github.com/user/repo", "github.com/user/repo"},
+	{"github.com/user/repo/", "github.com/user/repo"},
+	{"https:/github.com/user/repo", "github.com/user/repo"},
+	{"https://github.com/user/repo", "github.com/user/repo"},
+	{"HTTP:/www.github.com/user/repo.git/", "github.com/user/repo"},
+	{"GitHub.com/User/Repo", "github.com/User/Repo"},
+	{"www.bitbucket.org/user/repo.git", "bitbucket.org/user/repo"},
+	{"github.com/user/repo.git/sub", "github.com/user/repo.git/sub"},
+	{"example.org/repo.git", "example.org/repo.git"},
+	{"www.example.org/pkg", "www.example.org/pkg"},
+	{"golang.org/x/net/context", "golang.org/x/net/context"},
+}
+
+func TestNormalizeImportPath(t *testing.T) {
+	for _, tt := range normalizeImportPathTests {
+		if out := normalizeImportPath(tt.in); out != tt.out {
+			t.Errorf("normalizeImportPath(%q) = %q, want %q", tt.in, out, tt.out)
+		}
+	}
+}
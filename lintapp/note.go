@@ -0,0 +1,92 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package lintapp
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"golang.org/x/net/context"
+	"google.golang.org/appengine"
+	"google.golang.org/appengine/datastore"
+	"google.golang.org/appengine/user"
+
+	"github.com/ReturnPath/gddo/gosrc"
+)
+
+// An operator note is shown as a banner on the pages of a package. Notes are
+// stored as Note entities with the import path as key name, apart from the
+// lint results, so that they are kept when the package is linted again.
+
+// maxNoteLength is the maximum length of a note in bytes.
+const maxNoteLength = 1000
+
+type packageNote struct {
+	Text    string `datastore:",noindex"`
+	Author  string
+	Updated time.Time
+}
+
+func noteKey(c context.Context, importPath string) *datastore.Key {
+	return datastore.NewKey(c, "Note", importPath, 0, nil)
+}
+
+// getNote returns the note of the package with the given import path, or
+// nil if it has none.
+func getNote(c context.Context, importPath string) (*packageNote, error) {
+	var note packageNote
+	if err := datastore.Get(c, noteKey(c, importPath), &note); err != nil {
+		if err == datastore.ErrNoSuchEntity {
+			err = nil
+		}
+		return nil, err
+	}
+	return &note, nil
+}
+
+// serveAdminNote sets the note of the package given by the importPath
+// parameter to the note parameter in a POST, or clears it if the note is
+// empty. A GET responds with the note.
+func serveAdminNote(w http.ResponseWriter, r *http.Request) error {
+	importPath := r.FormValue("importPath")
+	if err := checkPathLimits(importPath); err != nil {
+		return err
+	}
+	if !fetcher.ValidPath(importPath) {
+		return gosrc.NotFoundError{Message: "bad path"}
+	}
+	c := appengine.NewContext(r)
+	if r.Method != "POST" {
+		note, err := getNote(c, importPath)
+		if err != nil {
+			return err
+		}
+		if note == nil {
+			return &appError{Status: 404, Message: "No note for this package."}
+		}
+		return writeJSONResponse(w, 200, note)
+	}
+	text := strings.TrimSpace(r.FormValue("note"))
+	if len(text) > maxNoteLength {
+		return &appError{Status: 400, Message: "Note too long."}
+	}
+	if text == "" {
+		if err := datastore.Delete(c, noteKey(c, importPath)); err != nil && err != datastore.ErrNoSuchEntity {
+			return err
+		}
+		return writeTextResponse(w, 200, "Cleared note for "+importPath+".\n")
+	}
+	note := &packageNote{Text: text, Updated: now()}
+	if u := user.Current(c); u != nil {
+		note.Author = u.Email
+	}
+	if _, err := datastore.Put(c, noteKey(c, importPath), note); err != nil {
+		return err
+	}
+	return writeTextResponse(w, 200, "Stored note for "+importPath+".\n")
+}
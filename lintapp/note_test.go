@@ -0,0 +1,60 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package lintapp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"google.golang.org/appengine"
+	"google.golang.org/appengine/aetest"
+)
+
+func TestServeAdminNoteBadPath(t *testing.T) {
+	r, _ := http.NewRequest("POST", "/-/admin/note?importPath=notapath&note=x", nil)
+	if err := serveAdminNote(httptest.NewRecorder(), r); err == nil {
+		t.Error("serveAdminNote with bad path returned nil error")
+	}
+}
+
+func TestServeAdminNote(t *testing.T) {
+	i, err := aetest.NewInstance(nil)
+	if err != nil {
+		t.Skipf("App Engine development server not available: %v", err)
+	}
+	defer i.Close()
+	post := func(note string) {
+		form := url.Values{"importPath": {"github.com/user/repo"}, "note": {note}}
+		r, err := i.NewRequest("POST", "/-/admin/note", strings.NewReader(form.Encode()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if err := serveAdminNote(httptest.NewRecorder(), r); err != nil {
+			t.Fatalf("serveAdminNote(%q) returned %v", note, err)
+		}
+	}
+	r, err := i.NewRequest("GET", "/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := appengine.NewContext(r)
+
+	post("known noisy, legacy code")
+	note, err := getNote(c, "github.com/user/repo")
+	if err != nil || note == nil || note.Text != "known noisy, legacy code" {
+		t.Errorf("getNote after set = %v, %v", note, err)
+	}
+	post("")
+	note, err = getNote(c, "github.com/user/repo")
+	if err != nil || note != nil {
+		t.Errorf("getNote after clear = %v, %v; want nil", note, err)
+	}
+}
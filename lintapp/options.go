@@ -0,0 +1,115 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package lintapp
+
+import (
+	"bytes"
+	"go/build"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"regexp"
+)
+
+// lintOptions holds the request parameters that change which files are
+// linted. Results linted with non-default options are stored separately from
+// the default result for the package.
+//
+// The goos and goarch parameters select a target platform. When either is
+// set, only files matching the platform are linted, as decided by
+// go/build: GOOS and GOARCH file name suffixes (foo_linux.go, foo_arm64.go)
+// and build constraint comments (// +build and //go:build) are honored, and
+// files importing "C" are assumed to build with cgo enabled. Custom build
+// tags are not supported. The unset half of the pair defaults to the
+// platform of the server.
+//
+// Generated files are not linted unless the generated parameter is set to 1.
+//
+// At most maxFilesPerPackage files are linted unless the all parameter is set
+// to 1.
+type lintOptions struct {
+	GOOS      string
+	GOARCH    string
+	Generated bool
+	All       bool
+}
+
+var platformPat = regexp.MustCompile(`^[a-z0-9]{1,16}$`)
+
+func parseLintOptions(r *http.Request) (lintOptions, error) {
+	opts := lintOptions{
+		GOOS:      r.FormValue("goos"),
+		GOARCH:    r.FormValue("goarch"),
+		Generated: r.FormValue("generated") == "1",
+		All:       r.FormValue("all") == "1",
+	}
+	if opts.GOOS != "" && !platformPat.MatchString(opts.GOOS) {
+		return opts, &appError{Status: 400, Message: "Bad goos parameter."}
+	}
+	if opts.GOARCH != "" && !platformPat.MatchString(opts.GOARCH) {
+		return opts, &appError{Status: 400, Message: "Bad goarch parameter."}
+	}
+	return opts, nil
+}
+
+// Values returns the options encoded as query parameters.
+func (opts lintOptions) Values() url.Values {
+	v := url.Values{}
+	if opts.GOOS != "" {
+		v.Set("goos", opts.GOOS)
+	}
+	if opts.GOARCH != "" {
+		v.Set("goarch", opts.GOARCH)
+	}
+	if opts.Generated {
+		v.Set("generated", "1")
+	}
+	if opts.All {
+		v.Set("all", "1")
+	}
+	return v
+}
+
+// pageURL returns the URL of the page for importPath linted with opts, with
+// suffix appended to the path.
+func (opts lintOptions) pageURL(importPath, suffix string) string {
+	u := url.URL{Path: "/" + importPath + suffix, RawQuery: opts.Values().Encode()}
+	return u.String()
+}
+
+// key returns the datastore key name for importPath linted with opts.
+func (opts lintOptions) key(importPath string) string {
+	if q := opts.Values().Encode(); q != "" {
+		return importPath + "?" + q
+	}
+	return importPath
+}
+
+// matchFile reports whether f should be linted for the target platform.
+func (opts lintOptions) matchFile(f *File) bool {
+	if opts.GOOS == "" && opts.GOARCH == "" {
+		return true
+	}
+	ctxt := build.Default
+	if opts.GOOS != "" {
+		ctxt.GOOS = opts.GOOS
+	}
+	if opts.GOARCH != "" {
+		ctxt.GOARCH = opts.GOARCH
+	}
+	ctxt.CgoEnabled = true
+	ctxt.OpenFile = func(string) (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(f.Data)), nil
+	}
+	match, err := ctxt.MatchFile(".", f.Name)
+	if err != nil {
+		// Let the linter report the problem with the file.
+		return true
+	}
+	return match
+}
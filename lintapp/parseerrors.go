@@ -0,0 +1,54 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package lintapp
+
+import "net/http"
+
+// countParseErrors is true if the errors of files that golint cannot parse,
+// such as files using cgo or unusual build constraints, are shown as
+// problems by default. Otherwise they are listed separately and not
+// counted. The parseErrors request parameter overrides the default.
+var countParseErrors = false
+
+// unanalyzedFile is a file that could not be linted.
+type unanalyzedFile struct {
+	Name  string
+	Error string
+}
+
+// parseErrorsCounted reports whether the parse errors are shown as problems
+// in the response to r.
+func parseErrorsCounted(r *http.Request) bool {
+	switch r.FormValue("parseErrors") {
+	case "1":
+		return true
+	case "0":
+		return false
+	}
+	return countParseErrors
+}
+
+// separateParseErrors moves the parse errors in pkg to pkg.Unanalyzed unless
+// they are counted as problems in the response to r. Parse errors left in
+// the files are shown whatever their confidence.
+func separateParseErrors(r *http.Request, pkg *lintPackage) {
+	if parseErrorsCounted(r) {
+		return
+	}
+	for _, f := range pkg.Files {
+		j := 0
+		for _, p := range f.Problems {
+			if p.ParseError {
+				pkg.Unanalyzed = append(pkg.Unanalyzed, unanalyzedFile{Name: f.Name, Error: p.Text})
+				continue
+			}
+			f.Problems[j] = p
+			j++
+		}
+		f.Problems = f.Problems[:j]
+	}
+}
@@ -0,0 +1,49 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package lintapp
+
+import (
+	"net/http"
+	"testing"
+
+	"golang.org/x/net/context"
+)
+
+func TestSeparateParseErrors(t *testing.T) {
+	defer func(old bool) { countParseErrors = old }(countParseErrors)
+	files := []*File{
+		{Name: "a.go", Data: []byte("package a\n\nvar a_a int\n")},
+		{Name: "b.go", Data: []byte("package a\n\nfunc {\n")},
+	}
+	linted := &lintPackage{Path: "example.com/a"}
+	lintFiles(context.Background(), linted, files, nil)
+
+	tests := []struct {
+		url        string
+		count      bool
+		problems   int
+		unanalyzed int
+	}{
+		{"/example.com/a", false, 1, 1},
+		{"/example.com/a?parseErrors=1", false, 2, 0},
+		{"/example.com/a", true, 2, 0},
+		{"/example.com/a?parseErrors=0", true, 1, 1},
+	}
+	for _, tt := range tests {
+		countParseErrors = tt.count
+		r, _ := http.NewRequest("GET", tt.url, nil)
+		pkg := linted.clone()
+		filterByConfidence(r, pkg)
+		if n, _ := pkg.counts(); n != tt.problems || len(pkg.Unanalyzed) != tt.unanalyzed {
+			t.Errorf("%s with countParseErrors %v: %d problems and %d unanalyzed files, want %d and %d",
+				tt.url, tt.count, n, len(pkg.Unanalyzed), tt.problems, tt.unanalyzed)
+		}
+		if len(pkg.Unanalyzed) == 1 && pkg.Unanalyzed[0].Name != "b.go" {
+			t.Errorf("%s: Unanalyzed = %+v, want b.go", tt.url, pkg.Unanalyzed)
+		}
+	}
+}
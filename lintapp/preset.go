@@ -0,0 +1,131 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package lintapp
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/appengine"
+	"google.golang.org/appengine/datastore"
+)
+
+// A preset is a named set of package page filters stored as a Preset entity
+// with the token as key name. The short URL /v/<token>/<importPath>
+// redirects to the package page with the filters applied.
+
+// presetParams are the query parameters saved in a preset.
+var presetParams = []string{"minConfidence", "maxConfidence", "excludeFiles", "format", "rule", "flat", "sort", "context"}
+
+// maxPresetName is the maximum length of a preset name.
+const maxPresetName = 100
+
+type preset struct {
+	Name    string
+	Query   string `datastore:",noindex"`
+	Created time.Time
+}
+
+// presetQuery returns the preset parameters of r, or an error if one of
+// them is not valid.
+func presetQuery(r *http.Request) (url.Values, error) {
+	q := make(url.Values)
+	for _, name := range presetParams {
+		if v := r.FormValue(name); v != "" {
+			q.Set(name, v)
+		}
+	}
+	for _, name := range []string{"minConfidence", "maxConfidence"} {
+		if v := q.Get(name); v != "" {
+			if _, err := strconv.ParseFloat(v, 64); err != nil {
+				return nil, &appError{Status: 400, Message: "Bad " + name + " parameter."}
+			}
+		}
+	}
+	if _, err := parseExcludeFiles(r); err != nil {
+		return nil, err
+	}
+	if f := q.Get("format"); f != "" && renderers[f] == nil {
+		return nil, &appError{Status: 400, Message: "Unknown format."}
+	}
+	if id := q.Get("rule"); id != "" && rulesByID[id] == nil {
+		return nil, &appError{Status: 400, Message: "Unknown rule."}
+	}
+	if len(q) == 0 {
+		return nil, &appError{Status: 400, Message: "No filter parameters."}
+	}
+	return q, nil
+}
+
+// presetToken returns the token of a preset. Saving the same preset twice
+// returns the same token.
+func presetToken(name string, q url.Values) string {
+	h := sha1.New()
+	h.Write([]byte(name + "\n" + q.Encode()))
+	return hex.EncodeToString(h.Sum(nil))[:10]
+}
+
+// serveSave stores the filters in the query as a preset with the given name
+// and responds with its token and short URL.
+func serveSave(w http.ResponseWriter, r *http.Request) error {
+	if r.Method != "POST" {
+		return &appError{Status: 405}
+	}
+	name := strings.TrimSpace(r.FormValue("name"))
+	if len(name) > maxPresetName {
+		return &appError{Status: 400, Message: "Preset name too long."}
+	}
+	q, err := presetQuery(r)
+	if err != nil {
+		return err
+	}
+	token := presetToken(name, q)
+	c := appengine.NewContext(r)
+	k := datastore.NewKey(c, "Preset", token, 0, nil)
+	if _, err := datastore.Put(c, k, &preset{Name: name, Query: q.Encode(), Created: now()}); err != nil {
+		return err
+	}
+	return writeJSONResponse(w, 200, struct {
+		Token string
+		Name  string
+		URL   string
+	}{token, name, "/v/" + token + "/"})
+}
+
+// serveView redirects /v/<token>/<importPath> to the package page of the
+// import path with the filters of the preset.
+func serveView(w http.ResponseWriter, r *http.Request) error {
+	token, importPath := strings.TrimPrefix(r.URL.Path, "/v/"), ""
+	if i := strings.Index(token, "/"); i >= 0 {
+		token, importPath = token[:i], token[i+1:]
+	}
+	if importPath == "" {
+		return &appError{Status: 404, Message: "Add an import path to the preset URL.", Detail: "/v/" + token + "/<import path>"}
+	}
+	c := appengine.NewContext(r)
+	var p preset
+	if err := datastore.Get(c, datastore.NewKey(c, "Preset", token, 0, nil), &p); err != nil {
+		if err == datastore.ErrNoSuchEntity {
+			err = &appError{Status: 404, Message: "Preset not found."}
+		}
+		return err
+	}
+	http.Redirect(w, r, presetURL(importPath, p.Query), http.StatusFound)
+	return nil
+}
+
+// presetURL returns the package page URL of importPath with the preset
+// query.
+func presetURL(importPath, query string) string {
+	u := url.URL{Path: "/" + importPath, RawQuery: query}
+	return u.String()
+}
@@ -0,0 +1,66 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package lintapp
+
+import (
+	"net/http"
+	"testing"
+)
+
+var presetQueryTests = []struct {
+	query string
+	want  string
+	ok    bool
+}{
+	{"minConfidence=0.9&excludeFiles=*_test.go&format=json", "excludeFiles=%2A_test.go&format=json&minConfidence=0.9", true},
+	{"rule=receiver-name&importPath=example.com/a", "rule=receiver-name", true},
+	{"", "", false},
+	{"importPath=example.com/a", "", false},
+	{"minConfidence=high", "", false},
+	{"format=pdf", "", false},
+	{"rule=unknown", "", false},
+	{"excludeFiles=[", "", false},
+}
+
+func TestPresetQuery(t *testing.T) {
+	for _, tt := range presetQueryTests {
+		r, err := http.NewRequest("POST", "/-/save?"+tt.query, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		q, err := presetQuery(r)
+		if ok := err == nil; ok != tt.ok {
+			t.Errorf("presetQuery(%q) error = %v, want ok %v", tt.query, err, tt.ok)
+			continue
+		}
+		if err == nil && q.Encode() != tt.want {
+			t.Errorf("presetQuery(%q) = %q, want %q", tt.query, q.Encode(), tt.want)
+		}
+	}
+}
+
+func TestPresetToken(t *testing.T) {
+	r, _ := http.NewRequest("POST", "/-/save?format=json&minConfidence=0.9", nil)
+	a, _ := presetQuery(r)
+	r, _ = http.NewRequest("POST", "/-/save?minConfidence=0.9&format=json", nil)
+	b, _ := presetQuery(r)
+	if presetToken("team", a) != presetToken("team", b) {
+		t.Error("presetToken depends on parameter order")
+	}
+	if presetToken("team", a) == presetToken("other", a) {
+		t.Error("presetToken does not depend on name")
+	}
+	if n := len(presetToken("team", a)); n != 10 {
+		t.Errorf("len(presetToken) = %d, want 10", n)
+	}
+}
+
+func TestPresetURL(t *testing.T) {
+	if got, want := presetURL("github.com/user/repo", "format=json"), "/github.com/user/repo?format=json"; got != want {
+		t.Errorf("presetURL = %q, want %q", got, want)
+	}
+}
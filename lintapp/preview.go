@@ -0,0 +1,70 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package lintapp
+
+import (
+	"net/http"
+
+	"google.golang.org/appengine"
+
+	"github.com/ReturnPath/gddo/gosrc"
+)
+
+// previewCounts is the response of the preview endpoint.
+type previewCounts struct {
+	Total   int            `json:"total"`
+	PerFile map[string]int `json:"perFile"`
+}
+
+// servePreview responds with the number of problems of a package, in total
+// and by file, after applying the confidence, api and excludeFiles filters of
+// the package page. The stored result is used if there is one, so that a client
+// can tune the filters without fetching the problems each time.
+func servePreview(w http.ResponseWriter, r *http.Request) error {
+	importPath := r.FormValue("importPath")
+	if err := checkPathLimits(importPath); err != nil {
+		return err
+	}
+	if !fetcher.ValidPath(importPath) {
+		return gosrc.NotFoundError{Message: "bad path"}
+	}
+	opts, err := parseLintOptions(r)
+	if err != nil {
+		return err
+	}
+	exclude, err := parseExcludeFiles(r)
+	if err != nil {
+		return err
+	}
+	pkg, err := getPackage(appengine.NewContext(r), opts.key(importPath))
+	if pkg == nil && err == nil {
+		pkg, err = runLint(r, importPath, opts)
+	}
+	if err != nil {
+		return err
+	}
+	filterByConfidence(r, pkg)
+	if r.FormValue("api") == "1" {
+		filterAPI(pkg)
+	}
+	excludeFiles(pkg, exclude)
+	w.Header().Set("X-Lint-Filter", filterSummary(r, pkg, nil, "", exclude))
+	return writeJSONResponse(w, 200, countProblems(pkg))
+}
+
+// countProblems returns the number of problems in pkg in total and by file.
+// Files without problems are left out.
+func countProblems(pkg *lintPackage) *previewCounts {
+	counts := &previewCounts{PerFile: make(map[string]int)}
+	for _, f := range pkg.Files {
+		if n := len(f.Problems); n > 0 {
+			counts.PerFile[f.Name] = n
+			counts.Total += n
+		}
+	}
+	return counts
+}
@@ -0,0 +1,32 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package lintapp
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestCountProblems(t *testing.T) {
+	r, _ := http.NewRequest("GET", "/-/preview?importPath=github.com/a/b&minConfidence=0.5&excludeFiles=*_gen.go", nil)
+	pkg := &lintPackage{Path: "github.com/a/b", Files: []*lintFile{
+		{Name: "a.go", Problems: []*lintProblem{{Confidence: 0.9}, {Confidence: 0.6}, {Confidence: 0.2}}},
+		{Name: "b.go", Problems: []*lintProblem{{Confidence: 0.3}}},
+		{Name: "x_gen.go", Problems: []*lintProblem{{Confidence: 1}}},
+	}}
+	exclude, err := parseExcludeFiles(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	filterByConfidence(r, pkg)
+	excludeFiles(pkg, exclude)
+	want := &previewCounts{Total: 2, PerFile: map[string]int{"a.go": 2}}
+	if got := countProblems(pkg); !reflect.DeepEqual(got, want) {
+		t.Errorf("countProblems = %+v, want %+v", got, want)
+	}
+}
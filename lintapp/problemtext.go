@@ -0,0 +1,51 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package lintapp
+
+import (
+	"regexp"
+	"strings"
+)
+
+// volatileTextPatterns match the parts of problem texts that change across
+// revisions without a change of the problem, such as the positions in
+// parse errors. They are removed from the texts compared by
+// removeBaselineProblems and diffPackages.
+var volatileTextPatterns = []*regexp.Regexp{
+	// a.go:12:3: expected declaration, found 'IDENT' foo
+	regexp.MustCompile(`[\w./-]*\.go:\d+(?::\d+)?:?`),
+}
+
+// parseTextPatterns parses a space separated list of regular expressions
+// matching volatile parts of problem texts. Malformed expressions are
+// ignored.
+func parseTextPatterns(s string) []*regexp.Regexp {
+	var pats []*regexp.Regexp
+	for _, f := range strings.Fields(s) {
+		if pat, err := regexp.Compile(f); err == nil {
+			pats = append(pats, pat)
+		}
+	}
+	return pats
+}
+
+// normalizeProblemText returns text without the parts matching
+// volatileTextPatterns and with runs of spaces collapsed.
+func normalizeProblemText(text string) string {
+	for _, pat := range volatileTextPatterns {
+		text = pat.ReplaceAllString(text, "")
+	}
+	return strings.Join(strings.Fields(text), " ")
+}
+
+// diffText returns the text of p for matching problems across revisions.
+func (p *lintProblem) diffText() string {
+	if p.NormalizedText != "" {
+		return p.NormalizedText
+	}
+	return p.Text
+}
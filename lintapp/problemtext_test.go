@@ -0,0 +1,49 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package lintapp
+
+import (
+	"regexp"
+	"testing"
+)
+
+var normalizeProblemTextTests = []struct {
+	text, want string
+}{
+	{"var userId should be userID", "var userId should be userID"},
+	{"a.go:12:3: expected declaration, found 'IDENT' foo", "expected declaration, found 'IDENT' foo"},
+	{"sub/b_test.go:4: expected 'package', found 'EOF'", "expected 'package', found 'EOF'"},
+	{"exported func  F returns   unexported type", "exported func F returns unexported type"},
+	{"check took 35ms for T", "check took for T"},
+}
+
+func TestNormalizeProblemText(t *testing.T) {
+	defer func(old []*regexp.Regexp) { volatileTextPatterns = old }(volatileTextPatterns)
+	pats := parseTextPatterns(`\d+ms [`)
+	if len(pats) != 1 {
+		t.Fatalf("parseTextPatterns returned %d patterns, want 1", len(pats))
+	}
+	volatileTextPatterns = append(volatileTextPatterns, pats...)
+	for _, tt := range normalizeProblemTextTests {
+		if got := normalizeProblemText(tt.text); got != tt.want {
+			t.Errorf("normalizeProblemText(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestRemoveBaselineProblemsNormalized(t *testing.T) {
+	baseline := &lintPackage{Files: []*lintFile{{Name: "a.go", Problems: []*lintProblem{
+		{Text: "a.go:3:1: expected declaration", NormalizedText: "expected declaration"},
+	}}}}
+	pkg := &lintPackage{Files: []*lintFile{{Name: "a.go", Problems: []*lintProblem{
+		{Text: "a.go:9:1: expected declaration", NormalizedText: "expected declaration"},
+	}}}}
+	removeBaselineProblems(pkg, baseline)
+	if n, _ := pkg.counts(); n != 0 {
+		t.Errorf("after removeBaselineProblems, %d problems remain, want 0", n)
+	}
+}
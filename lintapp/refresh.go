@@ -0,0 +1,185 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package lintapp
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"golang.org/x/net/context"
+	"google.golang.org/appengine"
+	"google.golang.org/appengine/log"
+	"google.golang.org/appengine/memcache"
+	"google.golang.org/appengine/taskqueue"
+)
+
+const (
+	// refreshQueue is the task queue for refreshes. The empty string
+	// selects the default queue.
+	refreshQueue = ""
+
+	// refreshTaskPath is the path of the handler that runs refresh tasks.
+	refreshTaskPath = "/-/task/refresh"
+
+	// refreshPendingTTL is how long a package is shown as being refreshed
+	// if the task does not finish.
+	refreshPendingTTL = 10 * time.Minute
+)
+
+var refreshTemplate = parseTemplate("common.html", "refresh.html")
+
+func refreshPendingKey(key string) string {
+	return "refresh:" + key
+}
+
+// setRefreshPending records whether a refresh of the package stored under key
+// is in progress.
+func setRefreshPending(c context.Context, key string, pending bool) {
+	var err error
+	if pending {
+		err = memcache.Set(c, &memcache.Item{Key: refreshPendingKey(key), Value: []byte{1}, Expiration: refreshPendingTTL})
+	} else {
+		err = memcache.Delete(c, refreshPendingKey(key))
+	}
+	if err != nil && err != memcache.ErrCacheMiss {
+		log.Warningf(c, "Setting refresh pending for %s: %v", key, err)
+	}
+}
+
+// isRefreshPending reports whether a refresh of the package stored under key
+// is in progress.
+func isRefreshPending(c context.Context, key string) bool {
+	_, err := memcache.Get(c, refreshPendingKey(key))
+	return err == nil
+}
+
+// queueRefresh adds a task to refresh importPath linted with opts. The task
+// also gets the params, if any: with a callback parameter, a regressionAlert
+// is posted to the callback when the refresh increases the problem count,
+// within the minConfidence and maxConfidence parameters, by more than
+// regressionThreshold.
+func queueRefresh(c context.Context, importPath string, opts lintOptions, params url.Values) error {
+	values := opts.Values()
+	for k, v := range params {
+		values[k] = v
+	}
+	values.Set("importPath", importPath)
+	if _, err := taskqueue.Add(c, taskqueue.NewPOSTTask(refreshTaskPath, values), refreshQueue); err != nil {
+		return err
+	}
+	setRefreshPending(c, opts.key(importPath), true)
+	return nil
+}
+
+// serveRefresh queues a refresh of a package and responds with 202 and a
+// link to the package page. The optional callback parameter is the URL
+// notified of a regression, as for a push hook gating on lint results.
+func serveRefresh(w http.ResponseWriter, r *http.Request) error {
+	if r.Method != "POST" {
+		return &appError{Status: 405}
+	}
+	start := time.Now()
+	importPath := r.FormValue("importPath")
+	if importPath == "" {
+		return &appError{Status: 400, Message: "Missing importPath parameter."}
+	}
+	if err := checkPathLimits(importPath); err != nil {
+		return err
+	}
+	if !fetcher.ValidPath(importPath) {
+		return &appError{Status: 400, Message: "Invalid importPath parameter."}
+	}
+	opts, err := parseLintOptions(r)
+	if err != nil {
+		return err
+	}
+	params := url.Values{}
+	if callback := r.FormValue("callback"); callback != "" {
+		if err := checkCallbackURL(callback); err != nil {
+			return err
+		}
+		params.Set("callback", callback)
+	}
+	for _, name := range []string{"minConfidence", "maxConfidence"} {
+		if v := r.FormValue(name); v != "" {
+			params.Set(name, v)
+		}
+	}
+	c := appengine.NewContext(r)
+	if err := queueRefresh(c, importPath, opts, params); err != nil {
+		return err
+	}
+	recordEvent(c, start, &Event{Name: "refresh", Path: importPath, Outcome: "queued"})
+
+	pageURL := opts.pageURL(importPath, "")
+	w.Header().Set("Location", pageURL)
+	return writeResponse(w, r, http.StatusAccepted, refreshTemplate, struct{ Path, PageURL string }{importPath, pageURL})
+}
+
+// serveRefreshTask runs a refresh queued by serveRefresh or for a job. Errors
+// other than a missing package are returned so that the task is retried. The
+// outcome of a job's task is recorded with putJobResult.
+func serveRefreshTask(w http.ResponseWriter, r *http.Request) error {
+	if r.Header.Get("X-AppEngine-QueueName") == "" {
+		return &appError{Status: 403}
+	}
+	c := appengine.NewContext(r)
+	start := time.Now()
+	importPath := r.FormValue("importPath")
+	opts, err := parseLintOptions(r)
+	if err != nil {
+		log.Errorf(c, "Dropping refresh of %s: %v", importPath, err)
+		return nil
+	}
+	key := opts.key(importPath)
+	callback := r.FormValue("callback")
+	if callback != "" {
+		// The allowed hosts may have changed since the task was queued.
+		if err := checkCallbackURL(callback); err != nil {
+			log.Warningf(c, "Dropping callback %s for refresh of %s: %v", callback, importPath, err)
+			callback = ""
+		}
+	}
+	var prev *lintPackage
+	if callback != "" {
+		if prev, err = getPackage(c, key); err != nil {
+			log.Warningf(c, "Getting previous result for %s: %v", importPath, err)
+		}
+	}
+	pkg, err := runLint(r, importPath, opts)
+	if isNotFound(err) {
+		log.Infof(c, "Dropping refresh of %s: %v", importPath, err)
+		setRefreshPending(c, key, false)
+		pkg, err = nil, nil
+	} else if err != nil {
+		return err
+	} else {
+		setRefreshPending(c, key, false)
+		recordEvent(c, start, &Event{Name: "refresh", Path: pkg.Path, Outcome: "ok"})
+		if a := newRegressionAlert(r, prev, pkg); a != nil {
+			if err := postRegressionAlert(c, callback, a); err != nil {
+				log.Warningf(c, "Posting regression of %s: %v", importPath, err)
+			}
+		}
+	}
+	if s := r.FormValue("job"); s != "" {
+		id, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			log.Errorf(c, "Bad job %q for refresh of %s", s, importPath)
+			return nil
+		}
+		if pkg != nil {
+			filterByConfidence(r, pkg)
+		}
+		if err := putJobResult(c, id, importPath, pkg); err != nil {
+			log.Warningf(c, "Recording result of %s for job %d: %v", importPath, id, err)
+		}
+	}
+	return nil
+}
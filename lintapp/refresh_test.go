@@ -0,0 +1,27 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package lintapp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestServeRefreshBadImportPath(t *testing.T) {
+	for _, importPath := range []string{"", "example", "github.com/" + strings.Repeat("a/", maxPathSegments)} {
+		form := url.Values{"importPath": {importPath}}
+		r, _ := http.NewRequest("POST", "/-/refresh", strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		err := serveRefresh(httptest.NewRecorder(), r)
+		if e, ok := err.(*appError); !ok || e.Status != 400 {
+			t.Errorf("serveRefresh with importPath %q returned %v, want 400 appError", importPath, err)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package lintapp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/context"
+	"google.golang.org/appengine/urlfetch"
+)
+
+// regressionThreshold is the increase of the problem count of a refreshed
+// package above which the refresh callback is notified.
+var regressionThreshold = 0
+
+// regressionAlert is posted as JSON to the callback URL of a refresh when the
+// problem count of the package increased by more than regressionThreshold.
+type regressionAlert struct {
+	Path     string
+	Previous int
+	Current  int
+	Delta    int
+	Message  string
+	PageURL  string
+}
+
+// callbackHosts is the set of hosts, with the port if there is one in the
+// URL, to which refresh callbacks may be posted. Callbacks are rejected when
+// it is empty, so that the server cannot be used to reach arbitrary hosts.
+var callbackHosts map[string]bool
+
+// parseCallbackHosts parses a comma separated list of hosts.
+func parseCallbackHosts(s string) map[string]bool {
+	m := make(map[string]bool)
+	for _, host := range strings.Split(s, ",") {
+		if host = strings.ToLower(strings.TrimSpace(host)); host != "" {
+			m[host] = true
+		}
+	}
+	return m
+}
+
+// checkCallbackURL returns an error if s is not an absolute http or https
+// URL on one of the callbackHosts.
+func checkCallbackURL(s string) error {
+	u, err := url.Parse(s)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return &appError{Status: 400, Message: "Invalid callback parameter."}
+	}
+	if !callbackHosts[strings.ToLower(u.Host)] {
+		return &appError{Status: 403, Message: "Callbacks to " + u.Host + " are not allowed.", Detail: "The server posts refresh callbacks only to the hosts its operator configured in CALLBACK_HOSTS."}
+	}
+	return nil
+}
+
+// newRegressionAlert returns the alert for a refresh of prev to pkg, with
+// the problems filtered as for r, or nil if the problem count did not
+// increase by more than regressionThreshold. There is no alert for a package
+// linted for the first time.
+func newRegressionAlert(r *http.Request, prev, pkg *lintPackage) *regressionAlert {
+	if prev == nil {
+		return nil
+	}
+	prev, pkg = prev.clone(), pkg.clone()
+	filterByConfidence(r, prev)
+	filterByConfidence(r, pkg)
+	before, _ := prev.counts()
+	after, _ := pkg.counts()
+	delta := after - before
+	if delta <= regressionThreshold {
+		return nil
+	}
+	return &regressionAlert{
+		Path:     pkg.Path,
+		Previous: before,
+		Current:  after,
+		Delta:    delta,
+		Message:  fmt.Sprintf("lint regressed by %d", delta),
+		PageURL:  "http://" + r.Host + pkg.PageURL(""),
+	}
+}
+
+// postRegressionAlert posts a to the callback URL.
+func postRegressionAlert(c context.Context, callback string, a *regressionAlert) error {
+	body, err := json.Marshal(a)
+	if err != nil {
+		return err
+	}
+	resp, err := urlfetch.Client(c).Post(callback, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		p, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("regression callback: %d %s", resp.StatusCode, p)
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package lintapp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func problemsPackage(confidences ...float64) *lintPackage {
+	f := &lintFile{Name: "a.go"}
+	for _, c := range confidences {
+		f.Problems = append(f.Problems, &lintProblem{Text: "x", Confidence: c})
+	}
+	return &lintPackage{Path: "example.com/a", Files: []*lintFile{f}}
+}
+
+func TestNewRegressionAlert(t *testing.T) {
+	defer func(old int) { regressionThreshold = old }(regressionThreshold)
+	r, _ := http.NewRequest("POST", "/-/task/refresh?minConfidence=0.5", nil)
+	r.Host = "lint.example.com"
+
+	prev := problemsPackage(1)
+	pkg := problemsPackage(1, 0.9, 0.8, 0.2)
+	if a := newRegressionAlert(r, nil, pkg); a != nil {
+		t.Errorf("alert for a first lint run: %+v", a)
+	}
+	a := newRegressionAlert(r, prev, pkg)
+	if a == nil {
+		t.Fatal("no alert for an increase of 2")
+	}
+	if a.Previous != 1 || a.Current != 3 || a.Delta != 2 || a.Message != "lint regressed by 2" {
+		t.Errorf("alert = %+v, want 1 -> 3", a)
+	}
+	if a.PageURL != "http://lint.example.com/example.com/a" {
+		t.Errorf("alert PageURL = %q", a.PageURL)
+	}
+	if n, _ := pkg.counts(); n != 4 {
+		t.Errorf("newRegressionAlert modified the package, %d problems remain", n)
+	}
+
+	regressionThreshold = 2
+	if a := newRegressionAlert(r, prev, pkg); a != nil {
+		t.Errorf("alert for an increase of 2 with threshold 2: %+v", a)
+	}
+	if a := newRegressionAlert(r, pkg, prev); a != nil {
+		t.Errorf("alert for a decrease: %+v", a)
+	}
+}
+
+func TestServeRefreshBadCallback(t *testing.T) {
+	for _, callback := range []string{"ftp://example.com/hook", "/hook", "http://"} {
+		form := url.Values{"importPath": {"github.com/user/repo"}, "callback": {callback}}
+		r, _ := http.NewRequest("POST", "/-/refresh", strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		err := serveRefresh(httptest.NewRecorder(), r)
+		if e, ok := err.(*appError); !ok || e.Status != 400 {
+			t.Errorf("serveRefresh with callback %q returned %v, want 400 appError", callback, err)
+		}
+	}
+}
+
+func TestCheckCallbackURL(t *testing.T) {
+	defer func(old map[string]bool) { callbackHosts = old }(callbackHosts)
+	callbackHosts = parseCallbackHosts("hooks.example.com, CI.example.com:8080,")
+	tests := []struct {
+		callback string
+		status   int
+	}{
+		{"https://hooks.example.com/lint", 0},
+		{"http://ci.example.com:8080/lint", 0},
+		{"http://ci.example.com/lint", 403},
+		{"http://169.254.169.254/computeMetadata/v1/", 403},
+		{"https://hooks.example.com.evil.com/lint", 403},
+		{"ftp://hooks.example.com/lint", 400},
+	}
+	for _, tt := range tests {
+		err := checkCallbackURL(tt.callback)
+		if tt.status == 0 {
+			if err != nil {
+				t.Errorf("checkCallbackURL(%q) = %v, want nil", tt.callback, err)
+			}
+			continue
+		}
+		if e, ok := err.(*appError); !ok || e.Status != tt.status {
+			t.Errorf("checkCallbackURL(%q) = %v, want %d appError", tt.callback, err, tt.status)
+		}
+	}
+	callbackHosts = parseCallbackHosts("")
+	if err := checkCallbackURL("https://hooks.example.com/lint"); err == nil {
+		t.Error("checkCallbackURL with no allowed hosts = nil, want error")
+	}
+}
@@ -0,0 +1,63 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package lintapp
+
+import (
+	"html/template"
+	"os"
+	"sync"
+	"time"
+
+	"google.golang.org/appengine"
+)
+
+// reloadTemplates reports whether templates are parsed again when their
+// files change. It is true on the development server, so that template
+// edits show without a restart, and a variable for testing.
+var reloadTemplates = appengine.IsDevAppServer
+
+// templateSources maps the templates returned by parseTemplate to their
+// files. The map is only written during package initialization.
+var templateSources = map[*template.Template]*templateSource{}
+
+type templateSource struct {
+	paths []string
+
+	mu      sync.Mutex
+	modTime time.Time // of the newest file at the latest parse
+	t       *template.Template
+}
+
+// reloadTemplate returns the latest parse of the files of t, parsing them
+// again if one was modified since the last parse.
+func reloadTemplate(t *template.Template) (*template.Template, error) {
+	src := templateSources[t]
+	if src == nil {
+		return t, nil
+	}
+	src.mu.Lock()
+	defer src.mu.Unlock()
+	modTime := src.modTime
+	for _, p := range src.paths {
+		fi, err := os.Stat(p)
+		if err != nil {
+			return nil, err
+		}
+		if fi.ModTime().After(modTime) {
+			modTime = fi.ModTime()
+		}
+	}
+	if modTime.Equal(src.modTime) {
+		return src.t, nil
+	}
+	nt, err := parseTemplateFiles(src.paths)
+	if err != nil {
+		return nil, err
+	}
+	src.t, src.modTime = nt, modTime
+	return nt, nil
+}
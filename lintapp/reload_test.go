@@ -0,0 +1,66 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package lintapp
+
+import (
+	"bytes"
+	"html/template"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestReloadTemplate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lintapp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	p := filepath.Join(dir, "a.html")
+	write := func(s string, mtime time.Time) {
+		if err := ioutil.WriteFile(p, []byte(`{{define "ROOT"}}`+s+`{{end}}`), 0666); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(p, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	execute := func(tmpl *template.Template) string {
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, nil); err != nil {
+			t.Fatal(err)
+		}
+		return buf.String()
+	}
+
+	t0 := time.Now().Add(-time.Hour)
+	write("one", t0)
+	orig, err := parseTemplateFiles([]string{p})
+	if err != nil {
+		t.Fatal(err)
+	}
+	templateSources[orig] = &templateSource{paths: []string{p}, modTime: t0.Add(time.Second), t: orig}
+	defer delete(templateSources, orig)
+
+	if got, err := reloadTemplate(orig); err != nil || execute(got) != "one" {
+		t.Fatalf("reloadTemplate of unmodified template = %v, %v", got, err)
+	}
+	write("two", t0.Add(time.Minute))
+	got, err := reloadTemplate(orig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := execute(got); s != "two" {
+		t.Errorf("reloadTemplate after edit executes to %q, want two", s)
+	}
+	write("{{", t0.Add(2*time.Minute))
+	if _, err := reloadTemplate(orig); err == nil {
+		t.Error("reloadTemplate of bad template returned nil error")
+	}
+}
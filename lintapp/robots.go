@@ -0,0 +1,82 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package lintapp
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Search engine exposure is configured with assets/robots.txt, served at
+// /robots.txt with an optional Crawl-delay, and with noindexMode, which adds
+// a noindex robots meta tag to pages. The robots.txt in the repository only
+// keeps crawlers out of /-/, so that noindexMode decides which package pages
+// are indexed:
+//
+//	""       no noindex tags
+//	"empty"  error pages and package pages without problems
+//	"all"    error pages and all package pages
+var (
+	noindexMode string
+	crawlDelay  int
+)
+
+// serveRobots serves assets/robots.txt with a Crawl-delay line for all user
+// agents if crawlDelay is set.
+func serveRobots(w http.ResponseWriter, r *http.Request) error {
+	data, err := ioutil.ReadFile("assets/robots.txt")
+	if err != nil {
+		return err
+	}
+	s := string(data)
+	if crawlDelay > 0 {
+		s = addCrawlDelay(s, crawlDelay)
+	}
+	setCacheControl(w, homeMaxAge)
+	return writeTextResponse(w, 200, s)
+}
+
+// addCrawlDelay adds a Crawl-delay line to the group for all user agents in
+// the robots.txt s, or a new group if there is none. Crawlers follow only one
+// group, so a second group for all user agents would be ignored.
+func addCrawlDelay(s string, delay int) string {
+	line := "Crawl-delay: " + strconv.Itoa(delay) + "\n"
+	lines := strings.SplitAfter(s, "\n")
+	for i, l := range lines {
+		if strings.EqualFold(strings.Join(strings.Fields(l), ""), "User-agent:*") {
+			if !strings.HasSuffix(l, "\n") {
+				lines[i] += "\n"
+			}
+			return strings.Join(lines[:i+1], "") + line + strings.Join(lines[i+1:], "")
+		}
+	}
+	if s != "" {
+		s = strings.TrimSuffix(s, "\n") + "\n\n"
+	}
+	return s + "User-agent: *\n" + line
+}
+
+// NoIndex reports whether the package page should not be indexed by search
+// engines.
+func (v *packageView) NoIndex() bool {
+	switch noindexMode {
+	case "all":
+		return true
+	case "empty":
+		problems, _ := v.counts()
+		return problems == 0
+	}
+	return false
+}
+
+// NoIndex reports whether the error page should not be indexed by search
+// engines.
+func (e *appError) NoIndex() bool {
+	return noindexMode != ""
+}
@@ -0,0 +1,63 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package lintapp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestServeRobots(t *testing.T) {
+	defer func(old int) { crawlDelay = old }(crawlDelay)
+	crawlDelay = 10
+
+	w := httptest.NewRecorder()
+	r, _ := http.NewRequest("GET", "/robots.txt", nil)
+	if err := serveRobots(w, r); err != nil {
+		t.Fatal(err)
+	}
+	if body := w.Body.String(); body != "User-agent: *\nCrawl-delay: 10\nDisallow: /-/\n" {
+		t.Errorf("body = %q, want assets/robots.txt with Crawl-delay: 10", body)
+	}
+}
+
+func TestAddCrawlDelay(t *testing.T) {
+	for _, tt := range []struct {
+		s, want string
+	}{
+		{"", "User-agent: *\nCrawl-delay: 5\n"},
+		{"User-agent: *\nDisallow: /-/\n", "User-agent: *\nCrawl-delay: 5\nDisallow: /-/\n"},
+		{"User-agent: *", "User-agent: *\nCrawl-delay: 5\n"},
+		{"User-agent: bot\nDisallow: /\n\nuser-agent : *\nAllow: /\n", "User-agent: bot\nDisallow: /\n\nuser-agent : *\nCrawl-delay: 5\nAllow: /\n"},
+		{"User-agent: bot\nDisallow: /\n", "User-agent: bot\nDisallow: /\n\nUser-agent: *\nCrawl-delay: 5\n"},
+	} {
+		if got := addCrawlDelay(tt.s, 5); got != tt.want {
+			t.Errorf("addCrawlDelay(%q, 5) = %q, want %q", tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestNoIndex(t *testing.T) {
+	defer func(old string) { noindexMode = old }(noindexMode)
+	empty := &packageView{lintPackage: &lintPackage{}}
+	problems := &packageView{lintPackage: &lintPackage{Files: []*lintFile{{Problems: []*lintProblem{{}}}}}}
+	for _, tt := range []struct {
+		mode                   string
+		empty, problems, error bool
+	}{
+		{"", false, false, false},
+		{"empty", true, false, true},
+		{"all", true, true, true},
+	} {
+		noindexMode = tt.mode
+		if empty.NoIndex() != tt.empty || problems.NoIndex() != tt.problems || (&appError{}).NoIndex() != tt.error {
+			t.Errorf("NoIndex with mode %q = %v, %v, %v; want %v, %v, %v", tt.mode,
+				empty.NoIndex(), problems.NoIndex(), (&appError{}).NoIndex(), tt.empty, tt.problems, tt.error)
+		}
+	}
+}
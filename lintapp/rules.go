@@ -0,0 +1,301 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package lintapp
+
+import (
+	"go/ast"
+	"net/http"
+	"regexp"
+)
+
+// codeReviewComments is the base URL of the style guide linked by golint.
+const codeReviewComments = "https://golang.org/wiki/CodeReviewComments"
+
+var rulesTemplate = parseTemplate("common.html", "rules.html")
+
+// rule classifies golint problems by message. golint does not report an
+// identifier for the check that found a problem, so the rules are matched
+// against the problem text.
+type rule struct {
+	ID          string
+	Description string
+	Example     string
+	Link        string `json:",omitempty"` // documentation of the rule
+	pat         *regexp.Regexp
+
+	// API is true for the rules selected by api=1, which concern the
+	// documentation and naming of the exported API. Problems of the naming
+	// rules marked with API are selected only for exported names.
+	API bool `json:",omitempty"`
+}
+
+// rules is the list of known rules. The first rule matching a problem
+// applies.
+var rules = []*rule{
+	{
+		ID:          "package-comment",
+		Description: "Packages should have a package comment of the form \"Package x ...\".",
+		Example:     "should have a package comment, unless it's in another file for this package",
+		Link:        codeReviewComments + "#package-comments",
+		pat:         regexp.MustCompile(`^(should have a package comment|package comment should)`),
+		API:         true,
+	},
+	{
+		ID:          "blank-import",
+		Description: "Blank imports belong in main or test packages, or need a comment justifying them.",
+		Example:     "a blank import should be only in a main or test package, or have a comment justifying it",
+		pat:         regexp.MustCompile(`^a blank import should be`),
+	},
+	{
+		ID:          "dot-import",
+		Description: "Dot imports should not be used.",
+		Example:     "should not use dot imports",
+		Link:        codeReviewComments + "#import-dot",
+		pat:         regexp.MustCompile(`^should not use dot imports`),
+	},
+	{
+		ID:          "exported-comment",
+		Description: "Exported identifiers should have a doc comment.",
+		Example:     "exported function Foo should have comment or be unexported",
+		Link:        codeReviewComments + "#doc-comments",
+		pat:         regexp.MustCompile(`^exported .* should have comment`),
+		API:         true,
+	},
+	{
+		ID:          "comment-form",
+		Description: "Doc comments on exported identifiers should start with the identifier's name.",
+		Example:     `comment on exported function Foo should be of the form "Foo ..."`,
+		Link:        codeReviewComments + "#doc-comments",
+		pat:         regexp.MustCompile(`^comment on exported .* should be of the form`),
+		API:         true,
+	},
+	{
+		ID:          "exported-declaration",
+		Description: "Exported variables and constants should have their own declaration.",
+		Example:     "exported var Foo should have its own declaration",
+		pat:         regexp.MustCompile(`^exported .* should have its own declaration`),
+		API:         true,
+	},
+	{
+		ID:          "package-underscore",
+		Description: "Package names should not contain underscores.",
+		Example:     "don't use an underscore in package name",
+		Link:        "https://golang.org/doc/effective_go.html#package-names",
+		pat:         regexp.MustCompile(`^don't use an underscore in package name`),
+	},
+	{
+		ID:          "all-caps",
+		Description: "Names should use MixedCaps, not ALL_CAPS.",
+		Example:     "don't use ALL_CAPS in Go names; use CamelCase",
+		Link:        codeReviewComments + "#mixed-caps",
+		pat:         regexp.MustCompile(`^don't use ALL_CAPS`),
+	},
+	{
+		ID:          "leading-k",
+		Description: "Names should not have a leading k.",
+		Example:     "don't use leading k in Go names; const kFoo should be foo",
+		Link:        codeReviewComments + "#mixed-caps",
+		pat:         regexp.MustCompile(`^don't use leading k`),
+	},
+	{
+		ID:          "underscore-name",
+		Description: "Names should use MixedCaps, not underscores.",
+		Example:     "don't use underscores in Go names; var foo_bar should be fooBar",
+		Link:        "https://golang.org/doc/effective_go.html#mixed-caps",
+		pat:         regexp.MustCompile(`^don't use underscores in Go names`),
+		API:         true,
+	},
+	{
+		ID:          "stutter",
+		Description: "Exported names should not repeat the package name.",
+		Example:     "type name will be used as foo.FooBar by other packages, and that stutters; consider calling this Bar",
+		Link:        codeReviewComments + "#package-names",
+		pat:         regexp.MustCompile(`and that stutters;`),
+		API:         true,
+	},
+	{
+		ID:          "receiver-name",
+		Description: "Receiver names should be short, consistent and not generic.",
+		Example:     "receiver name f should be consistent with previous receiver name foo for Foo",
+		Link:        codeReviewComments + "#receiver-names",
+		pat:         regexp.MustCompile(`^receiver name`),
+	},
+	{
+		ID:          "error-naming",
+		Description: "Error variables should be named errFoo or ErrFoo.",
+		Example:     "error var fooError should have name of the form errFoo",
+		pat:         regexp.MustCompile(`^error var .* should have name of the form`),
+	},
+	{
+		ID:          "error-strings",
+		Description: "Error strings should not be capitalized or end with punctuation.",
+		Example:     "error strings should not be capitalized or end with punctuation",
+		Link:        codeReviewComments + "#error-strings",
+		pat:         regexp.MustCompile(`^error strings should`),
+	},
+	{
+		ID:          "errorf",
+		Description: "Use fmt.Errorf instead of errors.New(fmt.Sprintf(...)).",
+		Example:     "should replace errors.New(fmt.Sprintf(...)) with fmt.Errorf(...)",
+		pat:         regexp.MustCompile(`^should replace .*\(fmt\.Sprintf\(\.\.\.\)\) with`),
+	},
+	{
+		ID:          "error-return",
+		Description: "An error should be the last value returned by a function.",
+		Example:     "error should be the last type when returning multiple items",
+		pat:         regexp.MustCompile(`^error should be the last type`),
+	},
+	{
+		ID:          "zero-value",
+		Description: "Variable declarations should not assign the zero value.",
+		Example:     "should drop = 0 from declaration of var x; it is the zero value",
+		pat:         regexp.MustCompile(`^should drop = .* it is the zero value`),
+	},
+	{
+		ID:          "type-inference",
+		Description: "Variable declarations should omit types that are inferred.",
+		Example:     "should omit type int from declaration of var x; it will be inferred from the right-hand side",
+		pat:         regexp.MustCompile(`^should omit type .* it will be inferred`),
+	},
+	{
+		ID:          "indent-error-flow",
+		Description: "Omit else blocks after if blocks that end in a return statement.",
+		Example:     "if block ends with a return statement, so drop this else and outdent its block",
+		Link:        codeReviewComments + "#indent-error-flow",
+		pat:         regexp.MustCompile(`^if block ends with a return statement`),
+	},
+	{
+		ID:          "range-loop",
+		Description: "Omit unneeded values from range clauses.",
+		Example:     "should omit 2nd value from range; this loop is equivalent to `for x := range ...`",
+		pat:         regexp.MustCompile(`^should omit 2nd value from range`),
+	},
+	{
+		ID:          "unary-op",
+		Description: "Use ++ and -- instead of += 1 and -= 1.",
+		Example:     "should replace x += 1 with x++",
+		pat:         regexp.MustCompile(`^should replace .* with .*(\+\+|--)$`),
+	},
+	{
+		ID:          "unexported-return",
+		Description: "Exported functions should not return unexported types.",
+		Example:     "exported func Foo returns unexported type *foo, which can be annoying to use",
+		pat:         regexp.MustCompile(`returns unexported type .* which can be annoying to use`),
+		API:         true,
+	},
+	{
+		ID:          "time-names",
+		Description: "time.Duration variables should not have unit suffixes.",
+		Example:     `var timeoutSecs is of type time.Duration; don't use unit-specific suffix "Secs"`,
+		pat:         regexp.MustCompile(`don't use unit-specific suffix`),
+	},
+	{
+		ID:          "context-keys-type",
+		Description: "Basic types should not be used as context.WithValue keys.",
+		Example:     "should not use basic type string as key in context.WithValue",
+		pat:         regexp.MustCompile(`as key in context\.WithValue`),
+	},
+	{
+		ID:          "context-as-argument",
+		Description: "context.Context should be the first parameter of a function.",
+		Example:     "context.Context should be the first parameter of a function",
+		Link:        "https://golang.org/pkg/context/",
+		pat:         regexp.MustCompile(`^context\.Context should be the first parameter`),
+	},
+	{
+		// Initialisms are matched last because the message is generic.
+		ID:          "initialisms",
+		Description: "Initialisms in names should have a consistent case, as in URL or ID.",
+		Example:     "func GetId should be GetID",
+		Link:        codeReviewComments + "#initialisms",
+		pat:         regexp.MustCompile(`^[a-z ]+ \S+ should be \S+$`),
+		API:         true,
+	},
+}
+
+// rulesByID maps rule IDs to rules.
+var rulesByID = map[string]*rule{}
+
+func init() {
+	for _, r := range rules {
+		rulesByID[r.ID] = r
+	}
+}
+
+// ruleID returns the ID of the first rule matching the problem text, or ""
+// if no rule matches.
+func ruleID(text string) string {
+	for _, r := range rules {
+		if r.pat.MatchString(text) {
+			return r.ID
+		}
+	}
+	return ""
+}
+
+// otherRuleID is the histogram key for problems not classified as a known
+// rule, such as parse errors.
+const otherRuleID = "other"
+
+// ruleHistogram returns the number of problems in pkg by rule ID.
+func ruleHistogram(pkg *lintPackage) map[string]int {
+	h := make(map[string]int)
+	for _, f := range pkg.Files {
+		for _, p := range f.Problems {
+			id := p.RuleID
+			if id == "" {
+				id = otherRuleID
+			}
+			h[id]++
+		}
+	}
+	return h
+}
+
+// serveRules responds with the list of rules, as JSON if negotiateFormat
+// chooses it.
+// The rule IDs are used in /<importPath>/rule/<id> URLs and in the
+// histogram and compare responses.
+func serveRules(w http.ResponseWriter, r *http.Request) error {
+	setCacheControl(w, homeMaxAge)
+	if negotiateFormat(r) == "json" {
+		return writeJSONResponse(w, 200, rules)
+	}
+	return writeResponse(w, r, 200, rulesTemplate, rules)
+}
+
+// namedIdentPat matches the name in the messages of the naming rules, as in
+// "func GetId should be GetID".
+var namedIdentPat = regexp.MustCompile(`(?:^|; )[a-z ]+ (\w+) should be \w+$`)
+
+// isAPIProblem reports whether p is selected by api=1: it is classified as
+// a rule marked with API and, for naming rules, names an exported
+// identifier.
+func isAPIProblem(p *lintProblem) bool {
+	r := rulesByID[p.RuleID]
+	if r == nil || !r.API {
+		return false
+	}
+	if m := namedIdentPat.FindStringSubmatch(p.Text); m != nil {
+		return ast.IsExported(m[1])
+	}
+	return true
+}
+
+// filterAPI removes the problems not selected by api=1.
+func filterAPI(pkg *lintPackage) {
+	for _, f := range pkg.Files {
+		j := 0
+		for i := range f.Problems {
+			if isAPIProblem(f.Problems[i]) {
+				f.Problems[j] = f.Problems[i]
+				j++
+			}
+		}
+		f.Problems = f.Problems[:j]
+	}
+}
@@ -0,0 +1,90 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package lintapp
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRuleExamples(t *testing.T) {
+	for _, r := range rules {
+		if id := ruleID(r.Example); id != r.ID {
+			t.Errorf("ruleID(%q) = %q, want %q", r.Example, id, r.ID)
+		}
+	}
+}
+
+func TestRuleIDUnknown(t *testing.T) {
+	if id := ruleID("expected 'package', found 'EOF'"); id != "" {
+		t.Errorf("ruleID of parse error = %q, want empty", id)
+	}
+}
+
+func TestRuleHistogram(t *testing.T) {
+	pkg := &lintPackage{Files: []*lintFile{
+		{Name: "a.go", Problems: []*lintProblem{{RuleID: "exported-comment"}, {RuleID: "receiver-name"}, {}}},
+		{Name: "b.go", Problems: []*lintProblem{{RuleID: "exported-comment"}}},
+	}}
+	want := map[string]int{"exported-comment": 2, "receiver-name": 1, otherRuleID: 1}
+	if got := ruleHistogram(pkg); !reflect.DeepEqual(got, want) {
+		t.Errorf("ruleHistogram = %v, want %v", got, want)
+	}
+}
+
+func TestServeRules(t *testing.T) {
+	for _, format := range []string{"json", "html"} {
+		r, _ := http.NewRequest("GET", "/-/rules?format="+format, nil)
+		w := httptest.NewRecorder()
+		if err := serveRules(w, r); err != nil {
+			t.Fatalf("serveRules(%s) returned %v", format, err)
+		}
+		if !strings.Contains(w.Body.String(), "receiver-name") {
+			t.Errorf("serveRules(%s) does not list receiver-name", format)
+		}
+	}
+	r, _ := http.NewRequest("GET", "/-/rules?format=json", nil)
+	w := httptest.NewRecorder()
+	serveRules(w, r)
+	var got []*rule
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(rules) {
+		t.Errorf("serveRules listed %d rules, want %d", len(got), len(rules))
+	}
+}
+
+func TestFilterAPI(t *testing.T) {
+	texts := []string{
+		"exported function Foo should have comment or be unexported",
+		"func GetId should be GetID",
+		"func getId should be getID",
+		"don't use underscores in Go names; func Foo_Bar should be FooBar",
+		"don't use underscores in Go names; var foo_bar should be fooBar",
+		"receiver name f should be consistent with previous receiver name foo for Foo",
+		"error strings should not be capitalized or end with punctuation",
+	}
+	var problems []*lintProblem
+	for _, text := range texts {
+		problems = append(problems, &lintProblem{Text: text, RuleID: ruleID(text)})
+	}
+	pkg := &lintPackage{Files: []*lintFile{{Name: "a.go", Problems: problems}}}
+	filterAPI(pkg)
+	var got []string
+	for _, p := range pkg.Files[0].Problems {
+		got = append(got, p.Text)
+	}
+	want := []string{texts[0], texts[1], texts[3]}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("filterAPI kept %q, want %q", got, want)
+	}
+}
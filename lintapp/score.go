@@ -0,0 +1,59 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package lintapp
+
+import (
+	"go/scanner"
+	"go/token"
+)
+
+// codeLines returns the number of lines in the Go source src that contain
+// code, not counting blank lines and lines with only comments.
+func codeLines(src []byte) int {
+	fset := token.NewFileSet()
+	file := fset.AddFile("", fset.Base(), len(src))
+	var s scanner.Scanner
+	s.Init(file, src, nil, 0)
+	n, last := 0, 0
+	for {
+		pos, tok, lit := s.Scan()
+		if tok == token.EOF {
+			return n
+		}
+		if tok == token.SEMICOLON && lit == "\n" {
+			// Automatically inserted semicolon.
+			continue
+		}
+		if line := file.Line(pos); line != last {
+			n++
+			last = line
+		}
+	}
+}
+
+// setScore sets pkg.Score from the problems in pkg with at least the default
+// minimum confidence and pkg.Lines:
+//
+//	Score = 100 * Lines / (Lines + 10 * problems)
+//
+// A package with no problems scores 100 and a package with one problem per
+// ten lines of code scores 50.
+func setScore(pkg *lintPackage) {
+	problems := 0
+	for _, f := range pkg.Files {
+		for _, p := range f.Problems {
+			if p.Confidence >= defaultMinConfidence {
+				problems++
+			}
+		}
+	}
+	if pkg.Lines+problems == 0 {
+		pkg.Score = 100
+		return
+	}
+	pkg.Score = 100 * float64(pkg.Lines) / float64(pkg.Lines+10*problems)
+}
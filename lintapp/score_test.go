@@ -0,0 +1,44 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package lintapp
+
+import "testing"
+
+func TestCodeLines(t *testing.T) {
+	src := `// Package p is a package.
+package p
+
+/*
+Block comment.
+*/
+
+// F does nothing.
+func F() {
+	x := 1 // trailing comment
+	_ = x
+}
+`
+	if n := codeLines([]byte(src)); n != 5 {
+		t.Errorf("codeLines = %d, want 5", n)
+	}
+}
+
+func TestSetScore(t *testing.T) {
+	pkg := &lintPackage{Lines: 10, Files: []*lintFile{{Problems: []*lintProblem{
+		{Confidence: 0.9},
+		{Confidence: 0.2},
+	}}}}
+	setScore(pkg)
+	if pkg.Score != 50 {
+		t.Errorf("Score = %v, want 50", pkg.Score)
+	}
+	pkg = &lintPackage{}
+	setScore(pkg)
+	if pkg.Score != 100 {
+		t.Errorf("Score of empty package = %v, want 100", pkg.Score)
+	}
+}
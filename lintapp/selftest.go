@@ -0,0 +1,69 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package lintapp
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// selfTestSource is linted by the self-test. It has one problem, reported
+// for line 5.
+const selfTestSource = `// Package selftest is linted by /-/selftest.
+package selftest
+
+// UserId returns the user ID.
+func UserId() int { return 0 }
+`
+
+// selfTestProblem is the problem expected in selfTestSource.
+var selfTestProblem = lintProblem{
+	Line:   5,
+	Text:   "func UserId should be UserID",
+	RuleID: "initialisms",
+}
+
+// selfTest lints selfTestSource and returns a description of the
+// difference from the expected problems, or "" if there is none.
+func selfTest() string {
+	problems, err := safeLint("selftest.go", []byte(selfTestSource))
+	if err != nil {
+		return "lint error: " + err.Error()
+	}
+	var found bool
+	var other []string
+	for _, p := range problems {
+		if p.Position.Line == selfTestProblem.Line && p.Text == selfTestProblem.Text {
+			found = true
+			if id := ruleID(p.Text); id != selfTestProblem.RuleID {
+				return fmt.Sprintf("problem classified as %q, want %q", id, selfTestProblem.RuleID)
+			}
+			continue
+		}
+		if p.Confidence >= defaultMinConfidence {
+			other = append(other, fmt.Sprintf("%d: %s", p.Position.Line, p.Text))
+		}
+	}
+	if !found {
+		return fmt.Sprintf("missing problem %d: %s", selfTestProblem.Line, selfTestProblem.Text)
+	}
+	if len(other) > 0 {
+		return fmt.Sprintf("unexpected problems %q", other)
+	}
+	return ""
+}
+
+// serveSelfTest responds with OK if golint finds the expected problem in a
+// fixture, and with 500 and the difference otherwise. It does not fetch or
+// store anything, for smoke tests after a deploy.
+func serveSelfTest(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Cache-Control", "no-cache")
+	if diff := selfTest(); diff != "" {
+		return writeTextResponse(w, 500, "FAIL: "+diff+"\n")
+	}
+	return writeTextResponse(w, 200, "OK\n")
+}
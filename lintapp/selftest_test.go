@@ -0,0 +1,30 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package lintapp
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/golang/lint"
+)
+
+func TestSelfTest(t *testing.T) {
+	if diff := selfTest(); diff != "" {
+		t.Errorf("selfTest() = %q, want empty", diff)
+	}
+}
+
+func TestSelfTestDetectsBrokenLinter(t *testing.T) {
+	defer func(old func(string, []byte) ([]lint.Problem, error)) { lintSource = old }(lintSource)
+	lintSource = func(filename string, src []byte) ([]lint.Problem, error) {
+		return nil, nil
+	}
+	if diff := selfTest(); !strings.HasPrefix(diff, "missing problem") {
+		t.Errorf("selfTest() with no problems = %q, want missing problem", diff)
+	}
+}
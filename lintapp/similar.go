@@ -0,0 +1,74 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package lintapp
+
+import (
+	"strings"
+	"time"
+
+	"golang.org/x/net/context"
+	"google.golang.org/appengine/log"
+	"google.golang.org/appengine/memcache"
+)
+
+// maxSimilarPackages is the number of other packages of the same repository
+// listed on a package page.
+const maxSimilarPackages = 5
+
+// repoPrefix returns the import path prefix of the repository of
+// importPath. The prefix is the host and two more elements, as in
+// github.com/user/repo and golang.org/x/net, or the whole path if it is
+// shorter.
+func repoPrefix(importPath string) string {
+	parts := strings.SplitN(importPath, "/", 4)
+	if len(parts) < 3 {
+		return importPath
+	}
+	return strings.Join(parts[:3], "/")
+}
+
+// similarPackages returns stored packages of the repository of importPath,
+// other than the package itself.
+func similarPackages(c context.Context, importPath string) ([]*packageSummary, error) {
+	summaries, err := store.ListPackages(c, repoPrefix(importPath), maxSimilarPackages+1)
+	if err != nil {
+		return nil, err
+	}
+	var result []*packageSummary
+	for _, s := range summaries {
+		if s.Path != importPath && len(result) < maxSimilarPackages {
+			result = append(result, s)
+		}
+	}
+	return result, nil
+}
+
+// similarPackagesExpiration is how long the similar packages of a package
+// are kept in memcache, so that package pages do not query the store on
+// every view.
+const similarPackagesExpiration = 10 * time.Minute
+
+// getSimilarPackages is like similarPackages, but caches the packages in
+// memcache.
+func getSimilarPackages(c context.Context, importPath string) ([]*packageSummary, error) {
+	mkey := "similar:" + importPath
+	var cached struct{ Packages []*packageSummary }
+	if _, err := memcache.Gob.Get(c, mkey, &cached); err == nil {
+		return cached.Packages, nil
+	} else if err != memcache.ErrCacheMiss {
+		log.Warningf(c, "Getting cached similar packages of %s: %v", importPath, err)
+	}
+	packages, err := similarPackages(c, importPath)
+	if err != nil {
+		return nil, err
+	}
+	cached.Packages = packages
+	if err := memcache.Gob.Set(c, &memcache.Item{Key: mkey, Object: &cached, Expiration: similarPackagesExpiration}); err != nil {
+		log.Warningf(c, "Caching similar packages of %s: %v", importPath, err)
+	}
+	return packages, nil
+}
@@ -0,0 +1,52 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package lintapp
+
+import (
+	"reflect"
+	"testing"
+
+	"golang.org/x/net/context"
+)
+
+func TestRepoPrefix(t *testing.T) {
+	tests := []struct{ in, out string }{
+		{"github.com/user/repo", "github.com/user/repo"},
+		{"github.com/user/repo/sub/pkg", "github.com/user/repo"},
+		{"golang.org/x/net/context", "golang.org/x/net"},
+		{"example.com/pkg", "example.com/pkg"},
+	}
+	for _, tt := range tests {
+		if out := repoPrefix(tt.in); out != tt.out {
+			t.Errorf("repoPrefix(%q) = %q, want %q", tt.in, out, tt.out)
+		}
+	}
+}
+
+func TestSimilarPackages(t *testing.T) {
+	defer func(old Store) { store = old }(store)
+	s := newMemoryStore()
+	store = s
+	c := context.Background()
+	problems := []*lintFile{{Name: "a.go", Problems: []*lintProblem{{Confidence: 1}, {Confidence: 0.2}}}}
+	for _, p := range []string{"github.com/user/repo", "github.com/user/repo/a", "github.com/user/repo/b", "github.com/user/repo-other", "github.com/user/other"} {
+		s.Put(c, p, &lintPackage{Path: p, Files: problems})
+	}
+	s.Put(c, "github.com/user/repo/c?goos=windows", &lintPackage{Path: "github.com/user/repo/c"})
+
+	got, err := similarPackages(c, "github.com/user/repo/a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []*packageSummary{
+		{Path: "github.com/user/repo", Problems: 1},
+		{Path: "github.com/user/repo/b", Problems: 1},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("similarPackages = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,102 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package lintapp
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"google.golang.org/appengine"
+
+	"github.com/ReturnPath/gddo/gosrc"
+)
+
+// sinceResponse is the response of the since endpoint.
+type sinceResponse struct {
+	Path    string
+	Updated time.Time
+
+	// Since is the update time of the snapshot the current result is
+	// compared with. It is zero if Full is set.
+	Since time.Time `json:",omitempty"`
+
+	// Full is true if no snapshot predates the requested time. Added then
+	// holds all current problems.
+	Full bool
+
+	Added   []*lintFile
+	Removed []*lintFile
+}
+
+// serveSince responds with the problems added and removed since the
+// snapshot of a package at the Unix time given by the since parameter, for
+// clients polling for changes.
+func serveSince(w http.ResponseWriter, r *http.Request) error {
+	importPath := r.FormValue("importPath")
+	if err := checkPathLimits(importPath); err != nil {
+		return err
+	}
+	if !fetcher.ValidPath(importPath) {
+		return gosrc.NotFoundError{Message: "bad path"}
+	}
+	since, err := strconv.ParseInt(r.FormValue("since"), 10, 64)
+	if err != nil {
+		return &appError{Status: 400, Message: "Bad since parameter."}
+	}
+	opts, err := parseLintOptions(r)
+	if err != nil {
+		return err
+	}
+	c := appengine.NewContext(r)
+	pkg, err := getPackage(c, opts.key(importPath))
+	if pkg == nil && err == nil {
+		pkg, err = runLint(r, importPath, opts)
+	}
+	if err != nil {
+		return err
+	}
+	pkg = pkg.clone()
+	filterByConfidence(r, pkg)
+
+	resp := &sinceResponse{Path: pkg.Path, Updated: pkg.Updated}
+	prev, err := store.GetSnapshotBefore(c, opts.key(pkg.Path), since)
+	if err != nil {
+		return err
+	}
+	if prev == nil {
+		resp.Full = true
+		resp.Added = pkg.Files
+	} else {
+		filterByConfidence(r, prev)
+		resp.Since = prev.Updated
+		resp.Added, resp.Removed = diffPackages(prev, pkg)
+	}
+	setCacheControl(w, freshMaxAge)
+	return writeJSONResponse(w, 200, resp)
+}
+
+// diffPackages returns the files with the problems in pkg that are not in
+// prev and the files with the problems in prev that are not in pkg. Files
+// without such problems are omitted.
+func diffPackages(prev, pkg *lintPackage) (added, removed []*lintFile) {
+	a := pkg.clone()
+	removeBaselineProblems(a, prev)
+	b := prev.clone()
+	removeBaselineProblems(b, pkg)
+	return nonEmptyFiles(a.Files), nonEmptyFiles(b.Files)
+}
+
+func nonEmptyFiles(files []*lintFile) []*lintFile {
+	var result []*lintFile
+	for _, f := range files {
+		if len(f.Problems) > 0 {
+			result = append(result, f)
+		}
+	}
+	return result
+}
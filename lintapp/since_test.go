@@ -0,0 +1,30 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package lintapp
+
+import "testing"
+
+func TestDiffPackages(t *testing.T) {
+	prev := &lintPackage{Files: []*lintFile{
+		{Name: "a.go", Problems: []*lintProblem{{Text: "x", LineText: "var x"}, {Text: "y", LineText: "var y"}}},
+		{Name: "b.go", Problems: []*lintProblem{{Text: "z", LineText: "var z"}}},
+	}}
+	pkg := &lintPackage{Files: []*lintFile{
+		{Name: "a.go", Problems: []*lintProblem{{Text: "x", LineText: "var x", Line: 10}, {Text: "w", LineText: "var w"}}},
+		{Name: "b.go", Problems: []*lintProblem{{Text: "z", LineText: "var z"}}},
+	}}
+	added, removed := diffPackages(prev, pkg)
+	if len(added) != 1 || added[0].Name != "a.go" || len(added[0].Problems) != 1 || added[0].Problems[0].Text != "w" {
+		t.Errorf("added = %+v, want w in a.go", added)
+	}
+	if len(removed) != 1 || removed[0].Name != "a.go" || len(removed[0].Problems) != 1 || removed[0].Problems[0].Text != "y" {
+		t.Errorf("removed = %+v, want y in a.go", removed)
+	}
+	if len(pkg.Files[0].Problems) != 2 || len(prev.Files[0].Problems) != 2 {
+		t.Error("diffPackages modified its arguments")
+	}
+}
@@ -0,0 +1,147 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package lintapp
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"time"
+
+	"golang.org/x/net/context"
+	"google.golang.org/appengine"
+	"google.golang.org/appengine/log"
+	"google.golang.org/appengine/memcache"
+)
+
+// maxSourceBytes is the size of the largest file shown in the source view.
+const maxSourceBytes = 256 << 10
+
+var sourceTemplate = parseTemplate("common.html", "source.html")
+
+// SourceURL returns the URL of the source view of the named file.
+func (pkg *lintPackage) SourceURL(name string) string {
+	v := pkg.Options.Values()
+	v.Set("view", "source")
+	v.Set("file", name)
+	u := url.URL{Path: "/" + pkg.Path, RawQuery: v.Encode()}
+	return u.String()
+}
+
+// sourceView is the data for the source template.
+type sourceView struct {
+	*packageView
+	File  string
+	URL   string
+	Lines []*sourceLine
+}
+
+type sourceLine struct {
+	Number   int
+	Text     string
+	Problems []*lintProblem
+}
+
+// sourceFileExpiration is how long a fetched file is kept in memcache for the
+// source view. Files are keyed by the lint result they are shown with.
+const sourceFileExpiration = 24 * time.Hour
+
+// errSourceChanged is returned by getSourceFile if the fetched file is not
+// the one that was linted, so the problems would be shown on the wrong lines.
+var errSourceChanged = &appError{
+	Status:  409,
+	Message: "The file has changed since it was linted.",
+	Detail:  "Lint the package again with nocache=1 to see the problems in the current source.",
+}
+
+// getSourceFile returns the contents of the named file of pkg, from memcache
+// or else fetched again, because sources are not stored. Fetches count as
+// lint runs for lintSem, so that source views cannot fetch more than the
+// instance lints. A fetched file that does not match the hash in pkg.Hashes
+// is not used.
+func getSourceFile(c context.Context, r *http.Request, pkg *lintPackage, name string) ([]byte, error) {
+	mkey := sourceFileKey(pkg, name)
+	if item, err := memcache.Get(c, mkey); err == nil {
+		return item.Value, nil
+	} else if err != memcache.ErrCacheMiss {
+		log.Warningf(c, "Getting cached source of %s in %s: %v", name, pkg.Path, err)
+	}
+
+	release, err := acquireLintSlot()
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	dir, err := fetchPackage(withHTTPClient(c, httpClient(r)), pkg.Path, "")
+	if err != nil {
+		return nil, err
+	}
+	var data []byte
+	for _, f := range dir.Files {
+		if f.Name == path.Base(name) {
+			data = f.Data
+		}
+	}
+	if h := pkg.Hashes[path.Base(name)]; data != nil && h != "" && fileHash(data) != h {
+		return nil, errSourceChanged
+	}
+	if data != nil && len(data) <= maxSourceBytes {
+		if err := memcache.Set(c, &memcache.Item{Key: mkey, Value: data, Expiration: sourceFileExpiration}); err != nil {
+			log.Warningf(c, "Caching source of %s in %s: %v", name, pkg.Path, err)
+		}
+	}
+	return data, nil
+}
+
+// sourceFileKey returns the memcache key of the named file of pkg. Import
+// paths can be longer than memcache allows, so the key is hashed.
+func sourceFileKey(pkg *lintPackage, name string) string {
+	h := sha1.Sum([]byte(fmt.Sprintf("%s\x00%s\x00%d", pkg.Path, name, pkg.Updated.UnixNano())))
+	return "source:" + hex.EncodeToString(h[:])
+}
+
+// serveSource writes the source of the named file of the package in v with
+// the problems shown below the lines they are reported on.
+func serveSource(w http.ResponseWriter, r *http.Request, v *packageView, name string) error {
+	var lf *lintFile
+	for _, f := range v.Files {
+		if f.Name == name {
+			lf = f
+		}
+	}
+	if lf == nil {
+		return &appError{Status: 404, Message: "No problems in file."}
+	}
+	data, err := getSourceFile(appengine.NewContext(r), r, v.lintPackage, name)
+	if err != nil {
+		return err
+	}
+	if data == nil {
+		return &appError{Status: 404, Message: "File not found."}
+	}
+	if len(data) > maxSourceBytes {
+		return &appError{Status: 400, Message: "File too large for the source view."}
+	}
+
+	lines := bytes.Split(data, []byte("\n"))
+	sv := &sourceView{packageView: v, File: name, URL: lf.URL, Lines: make([]*sourceLine, len(lines))}
+	for i, line := range lines {
+		sv.Lines[i] = &sourceLine{Number: i + 1, Text: string(bytes.TrimRight(line, "\r"))}
+	}
+	for _, p := range lf.Problems {
+		i := p.Line - 1
+		if i < 0 || i >= len(sv.Lines) {
+			i = 0
+		}
+		sv.Lines[i].Problems = append(sv.Lines[i].Problems, p)
+	}
+	return writeResponse(w, r, 200, sourceTemplate, sv)
+}
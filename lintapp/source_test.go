@@ -0,0 +1,70 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package lintapp
+
+import (
+	"testing"
+	"time"
+
+	"google.golang.org/appengine"
+	"google.golang.org/appengine/aetest"
+	"google.golang.org/appengine/memcache"
+)
+
+func TestGetSourceFileCached(t *testing.T) {
+	i, err := aetest.NewInstance(nil)
+	if err != nil {
+		t.Skipf("App Engine development server not available: %v", err)
+	}
+	defer i.Close()
+	r, err := i.NewRequest("GET", "/github.com/user/repo?view=source&file=a.go", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := appengine.NewContext(r)
+	pkg := &lintPackage{Path: "github.com/user/repo", Updated: time.Unix(1e9, 0)}
+	cached := []byte("package repo\n\nvar X_y int\n")
+	if err := memcache.Set(c, &memcache.Item{Key: sourceFileKey(pkg, "a.go"), Value: cached}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The cached file is served without fetching the package.
+	data, err := getSourceFile(c, r, pkg, "a.go")
+	if err != nil || string(data) != string(cached) {
+		t.Fatalf("getSourceFile = %q, %v; want the cached file", data, err)
+	}
+	// A newer lint result does not use the file cached for the older one.
+	newer := &lintPackage{Path: pkg.Path, Updated: pkg.Updated.Add(time.Hour)}
+	if sourceFileKey(newer, "a.go") == sourceFileKey(pkg, "a.go") {
+		t.Errorf("sourceFileKey is the same for results updated at different times")
+	}
+}
+
+func TestGetSourceFileChanged(t *testing.T) {
+	linted := []byte("package repo\n\nvar X_y int\n")
+	defer useFakeFetcher(fakeFetcher{"github.com/user/repo": {
+		ImportPath: "github.com/user/repo",
+		Files:      []*File{{Name: "a.go", Data: []byte("package repo\n\n// X_y is new.\nvar X_y int\n")}},
+	}})()
+	i, err := aetest.NewInstance(nil)
+	if err != nil {
+		t.Skipf("App Engine development server not available: %v", err)
+	}
+	defer i.Close()
+	r, err := i.NewRequest("GET", "/github.com/user/repo?view=source&file=a.go", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := appengine.NewContext(r)
+	pkg := &lintPackage{Path: "github.com/user/repo", Updated: time.Unix(1e9, 0), Hashes: map[string]string{"a.go": fileHash(linted)}}
+	if _, err := getSourceFile(c, r, pkg, "a.go"); err != errSourceChanged {
+		t.Errorf("getSourceFile of a changed file returned %v, want errSourceChanged", err)
+	}
+	if _, err := memcache.Get(c, sourceFileKey(pkg, "a.go")); err != memcache.ErrCacheMiss {
+		t.Errorf("changed file was cached, memcache.Get returned %v", err)
+	}
+}
@@ -0,0 +1,513 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package lintapp
+
+import (
+	"bytes"
+	"encoding/gob"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+	"google.golang.org/appengine/datastore"
+	"google.golang.org/appengine/log"
+)
+
+// Store stores lint results by key. The key is the import path, qualified
+// by the lint options used for the result.
+type Store interface {
+	// Get returns the package stored under key, or nil if there is no
+	// current package stored under key.
+	Get(c context.Context, key string) (*lintPackage, error)
+
+	// Put stores pkg under key and records a snapshot of pkg.
+	Put(c context.Context, key string, pkg *lintPackage) error
+
+	// PutMulti stores pkgs[i] under keys[i] as Put does, with fewer
+	// writes to the underlying storage.
+	PutMulti(c context.Context, keys []string, pkgs []*lintPackage) error
+
+	// GetSnapshot returns the snapshot of the package stored under key
+	// that was updated at the given Unix time, or nil if there is no such
+	// snapshot.
+	GetSnapshot(c context.Context, key string, updated int64) (*lintPackage, error)
+
+	// GetSnapshotBefore returns the most recent snapshot of the package
+	// stored under key that was updated at or before the given Unix time,
+	// or nil if there is no such snapshot.
+	GetSnapshotBefore(c context.Context, key string, t int64) (*lintPackage, error)
+
+	// GetSnapshots returns the stored snapshots of the package stored
+	// under key, oldest first.
+	GetSnapshots(c context.Context, key string) ([]*lintPackage, error)
+
+	// PutBaseline stores pkg as the baseline with the given name for the
+	// package stored under key.
+	PutBaseline(c context.Context, key, name string, pkg *lintPackage) error
+
+	// GetBaseline returns the baseline with the given name for the
+	// package stored under key, or nil if there is no such baseline.
+	GetBaseline(c context.Context, key, name string) (*lintPackage, error)
+
+	// ListPackages returns up to limit packages linted with the default
+	// options whose import path is prefix or below prefix, ordered by path.
+	ListPackages(c context.Context, prefix string, limit int) ([]*packageSummary, error)
+}
+
+// packageSummary is a stored package listed by Store.ListPackages.
+type packageSummary struct {
+	Path string
+
+	// Problems is the number of problems shown by default.
+	Problems int
+}
+
+// defaultProblemCount returns the number of problems in pkg shown with the
+// default minimum confidence for its path.
+func defaultProblemCount(pkg *lintPackage) int {
+	min := pathMinConfidence(pkg.Path)
+	n := 0
+	for _, f := range pkg.Files {
+		for _, p := range f.Problems {
+			if p.Confidence >= min {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+// inPrefix reports whether importPath is prefix or below prefix.
+func inPrefix(importPath, prefix string) bool {
+	return importPath == prefix || strings.HasPrefix(importPath, prefix+"/")
+}
+
+// store is the configured Store.
+var store Store = datastoreStore{}
+
+// datastoreStore stores gob encoded packages as Package entities in the App
+// Engine datastore.
+type datastoreStore struct{}
+
+// maxStoredBytes is the size above which packages are stored in compact
+// form, below the datastore limit of 1MB per entity.
+var maxStoredBytes = 900 << 10
+
+// compactStore is true if packages are always stored in compact form.
+var compactStore bool
+
+// encodePackage gob encodes pkg for storage. If the encoding is larger than
+// maxStoredBytes or compactStore is set, the compact form of pkg is encoded
+// instead.
+func encodePackage(pkg *lintPackage) ([]byte, error) {
+	var buf bytes.Buffer
+	if !compactStore {
+		if err := gob.NewEncoder(&buf).Encode(pkg); err != nil {
+			return nil, err
+		}
+		if buf.Len() <= maxStoredBytes {
+			return buf.Bytes(), nil
+		}
+		buf.Reset()
+	}
+	if err := gob.NewEncoder(&buf).Encode(compactPackage(pkg)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// compactPackage returns a copy of pkg without the source lines of the
+// problems, which dominate the size of noisy packages. The lines are shown
+// again by the annotated source view, which fetches the files.
+func compactPackage(pkg *lintPackage) *lintPackage {
+	pkg = pkg.clone()
+	pkg.Compact = true
+	for _, f := range pkg.Files {
+		for _, p := range f.Problems {
+			p.LineText, p.LineTextTruncated = "", false
+			p.Before, p.After = nil, nil
+		}
+	}
+	return pkg
+}
+
+// newStorePackages returns the Package and Snapshot entities of pkg stored
+// under key.
+func newStorePackages(key string, pkg *lintPackage) (spkg, snapshot *storePackage, err error) {
+	data, err := encodePackage(pkg)
+	if err != nil {
+		return nil, nil, err
+	}
+	spkg = &storePackage{Data: data, Version: version}
+	snapshot = &storePackage{Data: data, Version: version}
+	if key == pkg.Path {
+		spkg.Path = pkg.Path
+		spkg.Problems = defaultProblemCount(pkg)
+	}
+	return spkg, snapshot, nil
+}
+
+func (datastoreStore) Put(c context.Context, key string, pkg *lintPackage) error {
+	spkg, snapshot, err := newStorePackages(key, pkg)
+	if err != nil {
+		return err
+	}
+	k := datastore.NewKey(c, "Package", key, 0, nil)
+	// The package and its snapshot are in one entity group and are written
+	// in a transaction, so that an instance shut down during the write does
+	// not leave one without the other.
+	err = datastore.RunInTransaction(c, func(c context.Context) error {
+		_, err := datastore.PutMulti(c,
+			[]*datastore.Key{k, snapshotKey(c, k, pkg.Updated)},
+			[]*storePackage{spkg, snapshot})
+		return err
+	}, nil)
+	if err != nil {
+		return err
+	}
+	if err := pruneSnapshots(c, k); err != nil {
+		log.Warningf(c, "Pruning snapshots of %s: %v", key, err)
+	}
+	return nil
+}
+
+// maxPutMulti is the number of packages written in one transaction by
+// datastoreStore.PutMulti. Each package is its own entity group, and a
+// cross-group transaction is limited to 25 entity groups.
+const maxPutMulti = 25
+
+// maxPutMultiBytes bounds the encoded size of the packages and snapshots
+// written in one transaction by datastoreStore.PutMulti, below the 10 MiB
+// limit of a datastore commit.
+const maxPutMultiBytes = 8 << 20
+
+// putChunks splits the packages with the given encoded sizes into runs of at
+// most maxCount packages and maxBytes bytes and returns the end index of
+// each run. A package larger than maxBytes is written alone.
+func putChunks(sizes []int, maxCount, maxBytes int) []int {
+	var ends []int
+	count, bytes := 0, 0
+	for i, size := range sizes {
+		if count > 0 && (count == maxCount || bytes+size > maxBytes) {
+			ends = append(ends, i)
+			count, bytes = 0, 0
+		}
+		count++
+		bytes += size
+	}
+	if count > 0 {
+		ends = append(ends, len(sizes))
+	}
+	return ends
+}
+
+func (datastoreStore) PutMulti(c context.Context, keys []string, pkgs []*lintPackage) error {
+	dkeys := make([]*datastore.Key, 0, 2*len(keys))
+	spkgs := make([]*storePackage, 0, 2*len(keys))
+	sizes := make([]int, len(keys))
+	for i, key := range keys {
+		spkg, snapshot, err := newStorePackages(key, pkgs[i])
+		if err != nil {
+			return err
+		}
+		k := datastore.NewKey(c, "Package", key, 0, nil)
+		dkeys = append(dkeys, k, snapshotKey(c, k, pkgs[i].Updated))
+		spkgs = append(spkgs, spkg, snapshot)
+		sizes[i] = len(spkg.Data) + len(snapshot.Data)
+	}
+	start := 0
+	for _, end := range putChunks(sizes, maxPutMulti, maxPutMultiBytes) {
+		err := datastore.RunInTransaction(c, func(c context.Context) error {
+			_, err := datastore.PutMulti(c, dkeys[2*start:2*end], spkgs[2*start:2*end])
+			return err
+		}, &datastore.TransactionOptions{XG: true})
+		if err != nil {
+			return err
+		}
+		for j := 2 * start; j < 2*end; j += 2 {
+			if err := pruneSnapshots(c, dkeys[j]); err != nil {
+				log.Warningf(c, "Pruning snapshots of %s: %v", dkeys[j].StringID(), err)
+			}
+		}
+		start = end
+	}
+	return nil
+}
+
+// ListPackages queries the indexed Path and Problems properties, once for
+// the package at prefix and once for the range of paths below it. A single
+// range from prefix would also match paths extending the last element of
+// prefix, such as those of sibling repositories.
+func (datastoreStore) ListPackages(c context.Context, prefix string, limit int) ([]*packageSummary, error) {
+	var result []*packageSummary
+	// A property in an equality filter cannot be projected.
+	var exact []*storePackage
+	_, err := datastore.NewQuery("Package").
+		Filter("Path =", prefix).
+		Project("Problems").
+		Limit(1).
+		GetAll(c, &exact)
+	if err != nil {
+		return nil, err
+	}
+	for _, spkg := range exact {
+		result = append(result, &packageSummary{Path: prefix, Problems: spkg.Problems})
+	}
+	if len(result) >= limit {
+		return result[:limit], nil
+	}
+	var spkgs []*storePackage
+	_, err = datastore.NewQuery("Package").
+		Filter("Path >=", prefix+"/").
+		Filter("Path <", prefix+"0"). // '0' follows '/'
+		Project("Path", "Problems").
+		Limit(limit-len(result)).
+		GetAll(c, &spkgs)
+	if err != nil {
+		return nil, err
+	}
+	for _, spkg := range spkgs {
+		result = append(result, &packageSummary{Path: spkg.Path, Problems: spkg.Problems})
+	}
+	return result, nil
+}
+
+func (datastoreStore) Get(c context.Context, key string) (*lintPackage, error) {
+	var spkg storePackage
+	if err := datastore.Get(c, datastore.NewKey(c, "Package", key, 0, nil), &spkg); err != nil {
+		if err == datastore.ErrNoSuchEntity {
+			err = nil
+		}
+		return nil, err
+	}
+	if spkg.Version != version {
+		return decodeStalePackage(c, key, &spkg), nil
+	}
+	return decodePackage(&spkg)
+}
+
+func (datastoreStore) GetSnapshot(c context.Context, key string, updated int64) (*lintPackage, error) {
+	var spkg storePackage
+	parent := datastore.NewKey(c, "Package", key, 0, nil)
+	if err := datastore.Get(c, datastore.NewKey(c, "Snapshot", "", updated, parent), &spkg); err != nil {
+		if err == datastore.ErrNoSuchEntity {
+			err = nil
+		}
+		return nil, err
+	}
+	return decodePackage(&spkg)
+}
+
+func (s datastoreStore) GetSnapshotBefore(c context.Context, key string, t int64) (*lintPackage, error) {
+	// At most maxSnapshots snapshots are kept, so scan the keys instead of
+	// requiring a descending key index.
+	parent := datastore.NewKey(c, "Package", key, 0, nil)
+	keys, err := datastore.NewQuery("Snapshot").Ancestor(parent).KeysOnly().GetAll(c, nil)
+	if err != nil {
+		return nil, err
+	}
+	for i := len(keys) - 1; i >= 0; i-- {
+		if keys[i].IntID() <= t {
+			return s.GetSnapshot(c, key, keys[i].IntID())
+		}
+	}
+	return nil, nil
+}
+
+func (datastoreStore) GetSnapshots(c context.Context, key string) ([]*lintPackage, error) {
+	var spkgs []*storePackage
+	parent := datastore.NewKey(c, "Package", key, 0, nil)
+	if _, err := datastore.NewQuery("Snapshot").Ancestor(parent).GetAll(c, &spkgs); err != nil {
+		return nil, err
+	}
+	var pkgs []*lintPackage
+	for _, spkg := range spkgs {
+		pkg, err := decodePackage(spkg)
+		if err != nil {
+			return nil, err
+		}
+		if pkg != nil {
+			pkgs = append(pkgs, pkg)
+		}
+	}
+	return pkgs, nil
+}
+
+func (datastoreStore) PutBaseline(c context.Context, key, name string, pkg *lintPackage) error {
+	data, err := encodePackage(pkg)
+	if err != nil {
+		return err
+	}
+	parent := datastore.NewKey(c, "Package", key, 0, nil)
+	_, err = datastore.Put(c, datastore.NewKey(c, "Baseline", name, 0, parent), &storePackage{Data: data, Version: version})
+	return err
+}
+
+func (datastoreStore) GetBaseline(c context.Context, key, name string) (*lintPackage, error) {
+	var spkg storePackage
+	parent := datastore.NewKey(c, "Package", key, 0, nil)
+	if err := datastore.Get(c, datastore.NewKey(c, "Baseline", name, 0, parent), &spkg); err != nil {
+		if err == datastore.ErrNoSuchEntity {
+			err = nil
+		}
+		return nil, err
+	}
+	return decodePackage(&spkg)
+}
+
+// decodePackage decodes a stored package. It returns nil if the package was
+// stored with a different version.
+func decodePackage(spkg *storePackage) (*lintPackage, error) {
+	if spkg.Version != version {
+		return nil, nil
+	}
+	var pkg lintPackage
+	if err := gob.NewDecoder(bytes.NewReader(spkg.Data)).Decode(&pkg); err != nil {
+		return nil, err
+	}
+	return &pkg, nil
+}
+
+// decodeStalePackage logs and records a package stored with a different
+// version. The package is decoded if possible and returned marked as stale,
+// so that getPackage can serve it while a refresh runs and a lint run can
+// tell that it replaces the result of another version. Otherwise nil is
+// returned.
+func decodeStalePackage(c context.Context, key string, spkg *storePackage) *lintPackage {
+	log.Infof(c, "Version mismatch for %s: stored %d, current %d", key, spkg.Version, version)
+	recordEvent(c, time.Now(), &Event{Name: "version_mismatch", Path: key, Outcome: strconv.Itoa(spkg.Version)})
+	var pkg lintPackage
+	if err := gob.NewDecoder(bytes.NewReader(spkg.Data)).Decode(&pkg); err != nil {
+		log.Infof(c, "Decoding %s stored with version %d: %v", key, spkg.Version, err)
+		return nil
+	}
+	pkg.stale = true
+	return &pkg
+}
+
+// memoryStore stores packages in memory. It is used in tests and for
+// deployments that do not need results to outlive the instance.
+type memoryStore struct {
+	mu        sync.Mutex
+	packages  map[string]*lintPackage
+	snapshots map[string][]*lintPackage // oldest first
+	baselines map[string]*lintPackage   // by key and baseline name
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{
+		packages:  make(map[string]*lintPackage),
+		snapshots: make(map[string][]*lintPackage),
+		baselines: make(map[string]*lintPackage),
+	}
+}
+
+func (s *memoryStore) Put(c context.Context, key string, pkg *lintPackage) error {
+	pkg = pkg.clone()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.packages[key] = pkg
+	snapshots := append(s.snapshots[key], pkg)
+	if len(snapshots) > maxSnapshots {
+		snapshots = snapshots[len(snapshots)-maxSnapshots:]
+	}
+	s.snapshots[key] = snapshots
+	return nil
+}
+
+func (s *memoryStore) PutMulti(c context.Context, keys []string, pkgs []*lintPackage) error {
+	for i, key := range keys {
+		if err := s.Put(c, key, pkgs[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *memoryStore) ListPackages(c context.Context, prefix string, limit int) ([]*packageSummary, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var result []*packageSummary
+	for key, pkg := range s.packages {
+		if key == pkg.Path && inPrefix(key, prefix) {
+			result = append(result, &packageSummary{Path: key, Problems: defaultProblemCount(pkg)})
+		}
+	}
+	sort.Sort(bySummaryPath(result))
+	if len(result) > limit {
+		result = result[:limit]
+	}
+	return result, nil
+}
+
+type bySummaryPath []*packageSummary
+
+func (s bySummaryPath) Len() int           { return len(s) }
+func (s bySummaryPath) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s bySummaryPath) Less(i, j int) bool { return s[i].Path < s[j].Path }
+
+func (s *memoryStore) Get(c context.Context, key string) (*lintPackage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if pkg := s.packages[key]; pkg != nil {
+		return pkg.clone(), nil
+	}
+	return nil, nil
+}
+
+func (s *memoryStore) GetSnapshot(c context.Context, key string, updated int64) (*lintPackage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, pkg := range s.snapshots[key] {
+		if pkg.Updated.Unix() == updated {
+			return pkg.clone(), nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *memoryStore) GetSnapshotBefore(c context.Context, key string, t int64) (*lintPackage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	snapshots := s.snapshots[key]
+	for i := len(snapshots) - 1; i >= 0; i-- {
+		if snapshots[i].Updated.Unix() <= t {
+			return snapshots[i].clone(), nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *memoryStore) GetSnapshots(c context.Context, key string) ([]*lintPackage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var pkgs []*lintPackage
+	for _, pkg := range s.snapshots[key] {
+		pkgs = append(pkgs, pkg.clone())
+	}
+	return pkgs, nil
+}
+
+func (s *memoryStore) PutBaseline(c context.Context, key, name string, pkg *lintPackage) error {
+	pkg = pkg.clone()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.baselines[key+"\x00"+name] = pkg
+	return nil
+}
+
+func (s *memoryStore) GetBaseline(c context.Context, key, name string) (*lintPackage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if pkg := s.baselines[key+"\x00"+name]; pkg != nil {
+		return pkg.clone(), nil
+	}
+	return nil, nil
+}
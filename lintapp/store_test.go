@@ -0,0 +1,244 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package lintapp
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+	"google.golang.org/appengine"
+	"google.golang.org/appengine/aetest"
+)
+
+func testStore(t *testing.T, c context.Context, s Store) {
+	const key = "example.com/pkg"
+
+	pkg, err := s.Get(c, key)
+	if err != nil || pkg != nil {
+		t.Fatalf("Get before Put returned %v, %v; want nil, nil", pkg, err)
+	}
+
+	want := &lintPackage{
+		Path:    key,
+		Updated: time.Unix(1000, 0).UTC(),
+		Files: []*lintFile{{
+			Name:     "a.go",
+			Problems: []*lintProblem{{Line: 1, Text: "problem", Confidence: 0.9}},
+		}},
+	}
+	if err := s.Put(c, key, want); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := s.Get(c, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Get returned %+v, want %+v", got, want)
+	}
+
+	got.Files[0].Problems = nil
+	got, err = s.Get(c, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Get after modifying result returned %+v, want %+v", got, want)
+	}
+
+	got, err = s.GetSnapshot(c, key, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GetSnapshot returned %+v, want %+v", got, want)
+	}
+
+	got, err = s.GetSnapshot(c, key, 999)
+	if err != nil || got != nil {
+		t.Errorf("GetSnapshot of unknown snapshot returned %v, %v; want nil, nil", got, err)
+	}
+
+	got, err = s.GetSnapshotBefore(c, key, 1500)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GetSnapshotBefore returned %+v, want %+v", got, want)
+	}
+
+	got, err = s.GetSnapshotBefore(c, key, 999)
+	if err != nil || got != nil {
+		t.Errorf("GetSnapshotBefore of earlier time returned %v, %v; want nil, nil", got, err)
+	}
+
+	snapshots, err := s.GetSnapshots(c, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snapshots) != 1 || !reflect.DeepEqual(snapshots[0], want) {
+		t.Errorf("GetSnapshots returned %+v, want [%+v]", snapshots, want)
+	}
+
+	if err := s.PutBaseline(c, key, "v1", want); err != nil {
+		t.Fatal(err)
+	}
+	got, err = s.GetBaseline(c, key, "v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GetBaseline returned %+v, want %+v", got, want)
+	}
+	got, err = s.GetBaseline(c, key, "v2")
+	if err != nil || got != nil {
+		t.Errorf("GetBaseline of unknown baseline returned %v, %v; want nil, nil", got, err)
+	}
+
+	var keys []string
+	var pkgs []*lintPackage
+	for i := 0; i < maxPutMulti+2; i++ {
+		k := "example.com/multi/" + strconv.Itoa(i)
+		keys = append(keys, k)
+		pkgs = append(pkgs, &lintPackage{Path: k, Updated: time.Unix(2000, 0).UTC()})
+	}
+	if err := s.PutMulti(c, keys, pkgs); err != nil {
+		t.Fatal(err)
+	}
+	for i, k := range keys {
+		got, err := s.Get(c, k)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got == nil || got.Path != k || !got.Updated.Equal(pkgs[i].Updated) {
+			t.Errorf("Get after PutMulti returned %+v, want %+v", got, pkgs[i])
+		}
+		got, err = s.GetSnapshot(c, k, 2000)
+		if err != nil || got == nil {
+			t.Errorf("GetSnapshot after PutMulti returned %v, %v", got, err)
+		}
+	}
+}
+
+// testListPackages checks that ListPackages lists the package at the prefix
+// and below it, but not the packages of sibling repositories.
+func testListPackages(t *testing.T, c context.Context, s Store) {
+	for _, p := range []string{"example.com/repo", "example.com/repo/a", "example.com/repo/b", "example.com/repo-other", "example.com/repo.v2", "example.com/other"} {
+		if err := s.Put(c, p, &lintPackage{Path: p}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, tt := range []struct {
+		prefix string
+		limit  int
+		want   []string
+	}{
+		{"example.com/repo", 10, []string{"example.com/repo", "example.com/repo/a", "example.com/repo/b"}},
+		{"example.com/repo", 2, []string{"example.com/repo", "example.com/repo/a"}},
+		{"example.com/repo", 1, []string{"example.com/repo"}},
+		{"example.com/repo/a", 10, []string{"example.com/repo/a"}},
+		{"example.com/missing", 10, nil},
+	} {
+		summaries, err := s.ListPackages(c, tt.prefix, tt.limit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, sum := range summaries {
+			got = append(got, sum.Path)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ListPackages(%q, %d) = %v, want %v", tt.prefix, tt.limit, got, tt.want)
+		}
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	testStore(t, context.Background(), newMemoryStore())
+	testListPackages(t, context.Background(), newMemoryStore())
+}
+
+func TestDatastoreStore(t *testing.T) {
+	i, err := aetest.NewInstance(&aetest.Options{StronglyConsistentDatastore: true})
+	if err != nil {
+		t.Skipf("App Engine development server not available: %v", err)
+	}
+	defer i.Close()
+
+	r, err := i.NewRequest("GET", "/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testStore(t, appengine.NewContext(r), datastoreStore{})
+	testListPackages(t, appengine.NewContext(r), datastoreStore{})
+}
+
+func TestEncodePackageCompact(t *testing.T) {
+	defer func(old int) { maxStoredBytes = old }(maxStoredBytes)
+	pkg := &lintPackage{Path: "example.com/pkg", Files: []*lintFile{{
+		Name: "a.go",
+		Problems: []*lintProblem{{
+			Line:     3,
+			Text:     "problem",
+			LineText: strings.Repeat("x", 1000),
+			Before:   []string{"a", "b"},
+		}},
+	}}}
+
+	for _, tt := range []struct {
+		max     int
+		compact bool
+	}{
+		{1 << 20, false},
+		{500, true},
+	} {
+		maxStoredBytes = tt.max
+		data, err := encodePackage(pkg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := decodePackage(&storePackage{Data: data, Version: version})
+		if err != nil {
+			t.Fatal(err)
+		}
+		p := got.Files[0].Problems[0]
+		if got.Compact != tt.compact || (p.LineText == "") != tt.compact || (p.Before == nil) != tt.compact {
+			t.Errorf("with maxStoredBytes %d, decoded Compact = %v, LineText length %d, Before %v; want compact %v",
+				tt.max, got.Compact, len(p.LineText), p.Before, tt.compact)
+		}
+		if p.Text != "problem" || p.Line != 3 {
+			t.Errorf("with maxStoredBytes %d, decoded problem %+v", tt.max, p)
+		}
+	}
+	if pkg.Compact || pkg.Files[0].Problems[0].LineText == "" {
+		t.Error("encodePackage modified its argument")
+	}
+}
+
+func TestPutChunks(t *testing.T) {
+	for _, tt := range []struct {
+		sizes []int
+		want  []int
+	}{
+		{nil, nil},
+		{[]int{1, 1, 1, 1, 1}, []int{3, 5}},
+		{[]int{6, 5, 4, 1}, []int{1, 4}},
+		{[]int{6, 5, 4, 2}, []int{1, 3, 4}},
+		{[]int{20, 1}, []int{1, 2}},
+		{[]int{1, 20}, []int{1, 2}},
+	} {
+		got := putChunks(tt.sizes, 3, 10)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("putChunks(%v, 3, 10) = %v, want %v", tt.sizes, got, tt.want)
+		}
+	}
+}
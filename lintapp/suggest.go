@@ -0,0 +1,47 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package lintapp
+
+import "regexp"
+
+// fixPattern infers a fix from the text of a golint problem. The fix is the
+// expansion of template for the first match of pat.
+type fixPattern struct {
+	pat      *regexp.Regexp
+	template string
+}
+
+// fixPatterns is the list of messages that name their fix. The first
+// matching pattern applies.
+var fixPatterns = []fixPattern{
+	// var fooId should be fooID
+	// don't use underscores in Go names; func foo_bar should be fooBar
+	// don't use leading k in Go names; const kFoo should be foo
+	{regexp.MustCompile(`^(?:don't use (?:underscores|leading k) in Go names; )?[a-z ]+ \w+ should be (\w+)$`), "$1"},
+	// receiver name a should be consistent with previous receiver name b for T
+	{regexp.MustCompile(`^receiver name \w+ should be consistent with previous receiver name (\w+) for `), "$1"},
+	// comment on exported function Foo should be of the form "Foo ..."
+	// package comment should be of the form "Package foo ..."
+	{regexp.MustCompile(`^(?:comment on exported [a-z ]+ \S+|package comment) should be of the form "([^"]+)"`), "// $1"},
+	// should replace errors.New(fmt.Sprintf(...)) with fmt.Errorf(...)
+	// should replace x += 1 with x++
+	{regexp.MustCompile(`^should replace .+ with (.+)$`), "$1"},
+	// should omit 2nd value from range; this loop is equivalent to `for x := range ...`
+	{regexp.MustCompile("; this loop is equivalent to `([^`]+)`$"), "$1"},
+}
+
+// suggestFix returns the fix implied by the text of a golint problem, or ""
+// if the fix cannot be inferred from the text.
+func suggestFix(text string) string {
+	for _, f := range fixPatterns {
+		m := f.pat.FindStringSubmatchIndex(text)
+		if m != nil {
+			return string(f.pat.ExpandString(nil, f.template, text, m))
+		}
+	}
+	return ""
+}
@@ -0,0 +1,38 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package lintapp
+
+import "testing"
+
+var suggestFixTests = []struct {
+	text string
+	fix  string
+}{
+	{"var userId should be userID", "userID"},
+	{"struct field Url should be URL", "URL"},
+	{"don't use underscores in Go names; func foo_bar should be fooBar", "fooBar"},
+	{"don't use leading k in Go names; const kFoo should be foo", "foo"},
+	{"receiver name s should be consistent with previous receiver name srv for Server", "srv"},
+	{`comment on exported function Foo should be of the form "Foo ..."`, "// Foo ..."},
+	{`comment on exported type Foo should be of the form "Foo ..." (with optional leading article)`, "// Foo ..."},
+	{`package comment should be of the form "Package foo ..."`, "// Package foo ..."},
+	{"should replace errors.New(fmt.Sprintf(...)) with fmt.Errorf(...)", "fmt.Errorf(...)"},
+	{"should replace i += 1 with i++", "i++"},
+	{"should omit 2nd value from range; this loop is equivalent to `for k := range ...`", "for k := range ..."},
+	{"exported function Foo should have comment or be unexported", ""},
+	{"don't use ALL_CAPS in Go names; use CamelCase", ""},
+	{"if block ends with a return statement, so drop this else and outdent its block", ""},
+	{"expected 'package', found 'EOF'", ""},
+}
+
+func TestSuggestFix(t *testing.T) {
+	for _, tt := range suggestFixTests {
+		if fix := suggestFix(tt.text); fix != tt.fix {
+			t.Errorf("suggestFix(%q) = %q, want %q", tt.text, fix, tt.fix)
+		}
+	}
+}
@@ -0,0 +1,14 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+// +build !embedtemplates
+
+package lintapp
+
+// embeddedTemplates maps the names of the files in assets/templates to their
+// contents when built with the embedtemplates tag. Without the tag it is nil
+// and the templates are read from disk.
+var embeddedTemplates map[string]string
@@ -0,0 +1,63 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package lintapp
+
+import (
+	"html/template"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+func templateNames(t *template.Template) []string {
+	var names []string
+	for _, t := range t.Templates() {
+		names = append(names, t.Name())
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestParseEmbeddedTemplates(t *testing.T) {
+	defer func(old map[string]string) { embeddedTemplates = old }(embeddedTemplates)
+	paths, err := filepath.Glob("assets/templates/*.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	embeddedTemplates = make(map[string]string)
+	for _, p := range paths {
+		data, err := ioutil.ReadFile(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		embeddedTemplates[filepath.Base(p)] = string(data)
+	}
+
+	fromDisk, err := parseTemplateFiles([]string{"assets/templates/common.html", "assets/templates/package.html"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	embedded := parseTemplate("common.html", "package.html")
+	if got, want := templateNames(embedded), templateNames(fromDisk); len(got) != len(want) {
+		t.Errorf("embedded templates define %v, want %v", got, want)
+	} else {
+		for i := range got {
+			if got[i] != want[i] {
+				t.Errorf("embedded templates define %v, want %v", got, want)
+				break
+			}
+		}
+	}
+	if _, ok := templateSources[embedded]; ok {
+		t.Error("embedded template registered for reloading")
+	}
+
+	if _, err := parseEmbeddedTemplates([]string{"common.html", "missing.html"}); err == nil {
+		t.Error("parseEmbeddedTemplates of a missing template returned no error")
+	}
+}
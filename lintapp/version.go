@@ -0,0 +1,69 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package lintapp
+
+import (
+	"net/http"
+	"sync"
+
+	"golang.org/x/net/context"
+	"google.golang.org/appengine"
+)
+
+// buildVersion and buildCommit identify the deployed build. They are set at
+// build time with
+//
+//	-ldflags "-X github.com/ReturnPath/gddo/lintapp.buildVersion=v1.0 -X github.com/ReturnPath/gddo/lintapp.buildCommit=abc123"
+//
+// App Engine builds the app itself without the flags, so buildVersion is set
+// to the App Engine version by setDeployedVersion instead.
+var (
+	buildVersion = "devel"
+	buildCommit  = ""
+)
+
+// appVersionID returns the App Engine version of the app. It is a variable
+// for testing.
+var appVersionID = appengine.VersionID
+
+var deployedVersionOnce sync.Once
+
+// setDeployedVersion sets buildVersion to the App Engine version, as in
+// v2.394837261, if it was not set at build time. It is called by each
+// request before anything reads buildVersion.
+func setDeployedVersion(c context.Context) {
+	deployedVersionOnce.Do(func() {
+		if buildVersion == "devel" {
+			if v := appVersionID(c); v != "" {
+				buildVersion = v
+			}
+		}
+	})
+}
+
+// buildVersionFn returns the build version and commit for display.
+func buildVersionFn() string {
+	if buildCommit == "" {
+		return buildVersion
+	}
+	return buildVersion + " (" + buildCommit + ")"
+}
+
+// footerFn returns the operator's footer text, set with the FOOTER
+// environment variable. It is plain text, escaped by the templates.
+func footerFn() string {
+	return footerText
+}
+
+// serveVersion responds with the build version and commit as JSON, for
+// verifying deploys.
+func serveVersion(w http.ResponseWriter, r *http.Request) error {
+	return writeJSONResponse(w, 200, struct {
+		Version string `json:"version"`
+		Commit  string `json:"commit,omitempty"`
+	}{buildVersion, buildCommit})
+}
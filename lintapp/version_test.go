@@ -0,0 +1,51 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package lintapp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"golang.org/x/net/context"
+)
+
+func TestServeVersion(t *testing.T) {
+	defer func(v, c string) { buildVersion, buildCommit = v, c }(buildVersion, buildCommit)
+	buildVersion, buildCommit = "v1.2", "abc123"
+
+	w := httptest.NewRecorder()
+	r, _ := http.NewRequest("GET", "/-/version", nil)
+	if err := serveVersion(w, r); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := w.Body.String(), `{"version":"v1.2","commit":"abc123"}`; got != want {
+		t.Errorf("body = %s, want %s", got, want)
+	}
+	if got, want := buildVersionFn(), "v1.2 (abc123)"; got != want {
+		t.Errorf("buildVersionFn() = %q, want %q", got, want)
+	}
+}
+
+func TestSetDeployedVersion(t *testing.T) {
+	defer func(v string) { buildVersion = v }(buildVersion)
+	defer func(old func(context.Context) string) { appVersionID = old }(appVersionID)
+	appVersionID = func(context.Context) string { return "v2.394837261" }
+	tests := []struct{ build, want string }{
+		{"devel", "v2.394837261"},
+		{"v1.2", "v1.2"},
+	}
+	for _, tt := range tests {
+		deployedVersionOnce = sync.Once{}
+		buildVersion = tt.build
+		setDeployedVersion(context.Background())
+		if buildVersion != tt.want {
+			t.Errorf("buildVersion %q: set to %q, want %q", tt.build, buildVersion, tt.want)
+		}
+	}
+}